@@ -2,10 +2,9 @@ package api
 
 import (
 	"fmt"
-	"math/rand"
-	"strings"
 
 	"niuniu/app/model"
+	"niuniu/app/service"
 
 	"github.com/gogf/gf/errors/gerror"
 	"github.com/gogf/gf/util/gconv"
@@ -17,11 +16,14 @@ import (
 	"github.com/gogf/gf/container/garray"
 	"github.com/gogf/gf/container/gmap"
 	"github.com/gogf/gf/container/gset"
+	"github.com/gogf/gf/container/gtype"
 	"github.com/gogf/gf/encoding/ghtml"
 	"github.com/gogf/gf/encoding/gjson"
 	"github.com/gogf/gf/frame/g"
 	"github.com/gogf/gf/net/ghttp"
 	"github.com/gogf/gf/os/gcache"
+	"github.com/gogf/gf/os/gtime"
+	"github.com/gogf/gf/util/guid"
 )
 
 // 聊天管理器
@@ -32,48 +34,22 @@ type chatApi struct{}
 const (
 	// SendInterval 允许客户端发送聊天消息的间隔时间
 	sendInterval = time.Second
-	CachePaiName = "pai"
+	// 同时在线的最大人数,所有房间共用
+	maxUsers = 100
 )
 
-type UserPai struct {
-	Name      string
-	Num       int8        //点数,0-9为点数,10为牛牛,11为五朵金花
-	WinAndLos int8        //输还是赢,1胜,2负
-	Multiple  int8        //倍数
-	Max       string      //最大的牌
-	MaxNum    int         //最大牌的数值,52个
-	Pai       []string    //手上具体牌的数据
-	PaiNum    []int       //每个牌的点数
-	User      interface{} //具体的用户,用来发送消息的接口
-}
+// 聊天内容不是牌桌操作
+var errNotAction = gerror.New("不是牌桌操作")
 
 var (
-	users   = gmap.New(true)       // 使用默认的并发安全Map
-	names   = gset.NewStrSet(true) // 使用并发安全的Set，用以用户昵称唯一性校验
-	cache   = gcache.New()         // 使用特定的缓存对象，不使用全局缓存对象
-	basepai = []string{"A", "2", "3", "4", "5", "6", "7", "8", "9", "10", "J", "Q", "K"}
-	color   = []string{"黑桃", "红桃", "梅花", "方块"}
-	kin     = []string{"大王", "小王"}
-
-	paiusers = gmap.New(true) // 使用默认的并发安全Map
+	users = gmap.New(true)       // 使用默认的并发安全Map,key为*client,value为用户昵称
+	names = gset.NewStrSet(true) // 使用并发安全的Set，用以用户昵称唯一性校验
+	cache = gcache.New()         // 使用特定的缓存对象，不使用全局缓存对象
+	msgId = gtype.NewInt64()     // 服务端发出的消息ID,从1开始递增
+
 	//painame  = gset.NewStrSet(true) // 使用并发安全的Set，用以用户昵称唯一性校验
 )
 
-//初始化全部的牌,isd判断是否包含大小王,默认包含,如果值为1
-func paiinit(isd ...int) []string {
-	allpai := []string{}
-	for _, v := range color {
-		for _, i := range basepai {
-			allpai = append(allpai, v+i)
-		}
-	}
-	if len(isd) == 0 {
-		allpai = append(allpai, kin...)
-	}
-	//fmt.Println("初始化牌", allpai)
-	return allpai
-}
-
 // @summary 聊天室首页
 // @description 聊天室首页，只显示模板内容。如果当前用户未登录，那么引导跳转到名称设置页面。
 // @tags    聊天室
@@ -83,6 +59,8 @@ func paiinit(isd ...int) []string {
 func (a *chatApi) Index(r *ghttp.Request) {
 	view := r.GetView()
 	if r.Session.Contains("chat_name") {
+		// 以前设置的昵称还没有钱包时在这里开户,WebSocket连接之后会话就写不回客户端了
+		a.account(r)
 		view.Assign("tplMain", "chat/include/chat.html")
 	} else {
 		view.Assign("tplMain", "chat/include/main.html")
@@ -106,12 +84,18 @@ func (a *chatApi) SetName(r *ghttp.Request) {
 	}
 	name := ghtml.Entities(apiReq.Name)
 	r.Session.Set("chat_name_temp", name)
-	if names.Contains(name) {
+	// 断线还保留着座位的昵称也不能用,等原来的玩家重新连上;
+	// 同一个会话只有一个钱包,正在用的昵称还在线或者还在房间里时不能换昵称
+	if old := r.Session.GetString("chat_name"); old != "" && old != name && (names.Contains(old) || service.Room.Of(old) != nil) {
+		r.Session.Set("chat_name_error", fmt.Sprintf("您正在使用昵称%s,断开连接并离开房间后才能修改", old))
+		r.Response.RedirectBack()
+	} else if names.Contains(name) || service.Room.Of(name) != nil {
 		r.Session.Set("chat_name_error", "用户昵称已被占用")
 		r.Response.RedirectBack()
 	} else {
 		r.Session.Set("chat_name", name)
 		r.Session.Remove("chat_name_temp", "chat_name_error")
+		a.account(r)
 		r.Response.RedirectTo("/chat")
 	}
 }
@@ -128,7 +112,7 @@ func (a *chatApi) WebSocket(r *ghttp.Request) {
 		ws  *ghttp.WebSocket
 		err error
 	)
-	if users.Size() > 3 {
+	if users.Size() >= maxUsers {
 		response.JsonExit(r, 0, "ok")
 		return
 	}
@@ -143,29 +127,67 @@ func (a *chatApi) WebSocket(r *ghttp.Request) {
 		name = r.Request.RemoteAddr
 	}
 
-	// 初始化时设置用户昵称为当前链接信息
+	// 初始化时设置用户昵称为当前链接信息,消息都通过客户端的发送队列发出
+	// 没有钱包的连接(没有设置昵称)只能聊天与观战,不能用筹码
+	c := newClient(ws, name, r.Session.GetString("chat_wallet"))
 	names.Add(name)
-	users.Set(ws, name)
+	users.Set(c, name)
 
 	// 初始化后向所有客户端发送上线消息
 	a.writeUserListToClient()
 
+	// 断线重连时回到原来的座位,牌桌会先补发错过的消息再发快照,旧的连接还没断开时顶替旧的连接,
+	// 否则带着房号连接时直接加入该房间,带着watch参数时观战
+	if t := service.Room.Resume(name, c); t != nil {
+		a.write(c, model.ChatMsg{
+			Type: model.MsgSend,
+			Data: fmt.Sprintf("您重新连上了房间%d", t.ID()),
+			From: service.DealerName,
+		})
+	} else if code := r.GetString("code"); code != "" {
+		join := service.Room.JoinCode
+		if r.GetBool("watch") {
+			join = service.Room.WatchCode
+		}
+		if t, err := join(code, r.GetString("passcode"), name, c); err != nil {
+			a.write(c, model.ChatMsg{
+				Type: model.MsgError,
+				Data: err.Error(),
+				From: "",
+			})
+		} else {
+			a.write(c, model.ChatMsg{
+				Type: model.MsgSend,
+				Data: fmt.Sprintf("您进入了房间%d", t.ID()),
+				From: service.DealerName,
+			})
+		}
+	}
+
 	for {
 		// 阻塞读取WS数据
 		_, msgByte, err := ws.ReadMessage()
 		if err != nil {
 			// 如果失败，那么表示断开，这里清除用户信息
-			// 为简化演示，这里不实现失败重连机制
-			names.Remove(name)
-			users.Remove(ws)
+			// 坐下的玩家会保留座位,同一个会话重新连接时回到原来的座位
+			// 同一个会话在别处重新连上时昵称还在用,只清除自己的连接
+			users.Remove(c)
+			if !a.online(name) {
+				names.Remove(name)
+			}
+			c.Close()
+			// 离开所在的房间,坐下的玩家等断线重连超时后再让出座位,已经被新的连接顶替时不处理
+			service.Room.Quit(name, c)
 			// 通知所有客户端当前用户已下线
 			a.writeUserListToClient()
 			break
 		}
+		// 收到任何消息都说明连接还活着
+		c.touch()
 		// JSON参数解析
 		if err := gjson.DecodeTo(msgByte, msg); err != nil {
-			a.write(ws, model.ChatMsg{
-				Type: "error",
+			a.write(c, model.ChatMsg{
+				Type: model.MsgError,
 				Data: "消息格式不正确: " + err.Error(),
 				From: "",
 			})
@@ -173,27 +195,60 @@ func (a *chatApi) WebSocket(r *ghttp.Request) {
 		}
 		// 数据校验
 		if err := g.Validator().Ctx(r.Context()).CheckStruct(msg); err != nil {
-			a.write(ws, model.ChatMsg{
-				Type: "error",
+			a.write(c, model.ChatMsg{
+				Type: model.MsgError,
 				Data: gerror.Current(err).Error(),
 				From: "",
 			})
 			continue
 		}
+		// 客户端带了协议版本时检查版本,不带时按当前版本处理
+		if msg.Version != 0 && msg.Version != model.ProtocolVersion {
+			a.write(c, model.ChatMsg{
+				Type: model.MsgError,
+				Data: fmt.Sprintf("不支持的协议版本%d,当前版本为%d", msg.Version, model.ProtocolVersion),
+				From: "",
+			})
+			continue
+		}
 		msg.From = name
 
+		// 心跳只回复pong,不记录日志
+		if msg.Type == model.MsgPing {
+			a.write(c, model.ChatMsg{Type: model.MsgPong})
+			continue
+		}
+
 		// 日志记录
 		g.Log().Cat("chat").Println(msg)
 
 		// WS操作类型
 		switch msg.Type {
+		// 请求补发牌桌的消息,data为收到的最后一个序号
+		case model.MsgResume:
+			t := service.Room.Of(name)
+			if t == nil {
+				a.write(c, model.ChatMsg{
+					Type: model.MsgError,
+					Data: "您不在房间里",
+					From: "",
+				})
+				continue
+			}
+			if err := t.Replay(name, gconv.Int64(msg.Data)); err != nil {
+				a.write(c, model.ChatMsg{
+					Type: model.MsgError,
+					Data: err.Error(),
+					From: "",
+				})
+			}
 		// 发送消息
-		case "send":
+		case model.MsgSend:
 			// 发送间隔检查
 			intervalKey := fmt.Sprintf("%p", ws)
 			if ok, _ := cache.SetIfNotExist(intervalKey, struct{}{}, sendInterval); !ok {
-				a.write(ws, model.ChatMsg{
-					Type: "error",
+				a.write(c, model.ChatMsg{
+					Type: model.MsgError,
 					Data: "您的消息发送得过于频繁，请休息下再重试",
 					From: "",
 				})
@@ -202,348 +257,109 @@ func (a *chatApi) WebSocket(r *ghttp.Request) {
 			// 有消息时，群发消息
 			if msg.Data != nil {
 				dd := gconv.String(msg.Data)
-				//fmt.Println(gconv.String(msg.Data)),并且名称不能重复
-				if dd == "111" && paiusers.Size() != 2 && !isRepeat(name) {
-					//如果用户输入111,那么返回
-					paiusers.Set(ws, name) //把用户加到组里面,如果人数满3人,就开始发牌,并且清空原来的数组
-					if paiusers.Size() == 2 {
-						//开始发牌
-						if err = a.writeGroup1(); err != nil {
-							g.Log().Error(err)
-						}
-						//a.ending()
-					} else if err = a.writeGroup(
-						model.ChatMsg{
-							Type: "send",
-							Data: ghtml.SpecialChars("当前人数" + gconv.String(paiusers.Size())),
-							From: ghtml.SpecialChars(msg.From),
-						}); err != nil {
+				reply, err := a.action(c, name, dd)
+				if err == errNotAction {
+					//不是牌桌操作,在房间里时只发给同一桌的玩家,否则群发;昵称在SetName时已经转义过,这里只转义聊天内容
+					if t := service.Room.Of(name); t != nil {
+						err = t.Say(name, ghtml.SpecialChars(dd))
+					} else {
+						err = a.writeGroup(
+							model.ChatMsg{
+								Type: model.MsgSend,
+								Data: ghtml.SpecialChars(dd),
+								From: name,
+							})
+					}
+					if err != nil {
 						g.Log().Error(err)
 					}
-
-				} else if dd == "结果" || dd == "结束" {
-					a.ending()
-				} else if err = a.writeGroup(
-					model.ChatMsg{
-						Type: "send",
-						Data: ghtml.SpecialChars(dd),
-						From: ghtml.SpecialChars(msg.From),
-					}); err != nil {
-					g.Log().Error(err)
+				} else if err != nil {
+					a.write(c, model.ChatMsg{
+						Type: model.MsgError,
+						Data: err.Error(),
+						From: "",
+					})
+				} else if reply != "" {
+					a.write(c, model.ChatMsg{
+						Type: model.MsgSend,
+						Data: reply,
+						From: service.DealerName,
+					})
 				}
 			}
 		}
 	}
 }
 
-//判断用户是否重复输入111
-func isRepeat(name string) (b bool) {
-	paiusers.RLockFunc(func(m map[interface{}]interface{}) {
-		for _, v := range m {
-			if gconv.String(v) == name {
-				b = true
-			}
-		}
-	})
-	return
-}
-
-//进入发牌
-func (a *chatApi) writeGroup1() error {
-	pai := paiinit(1) //拿到去掉大小王的牌
-	//fmt.Println("基础的牌是", pai)
-	var b []byte
-	paiusers.RLockFunc(func(m map[interface{}]interface{}) {
-		fmt.Println(m)
-		for user, v := range m {
-
-			name := gconv.String(v)
-			uspai, newpai := fapai(pai)
-			//把牌存个十分钟进缓存
-			bo, _ := cache.Contains(CachePaiName + name)
-			if bo {
-				cache.Remove(CachePaiName + name)
-			}
-			num, _, _ := winAndLos(gconv.Strings(uspai))
-			st := gconv.String(uspai)
-			cache.Set("pai"+name, uspai, 1000*time.Minute)
-			msg := model.ChatMsg{
-				Type: "send",
-				Data: st + niu(num),
-				From: ghtml.SpecialChars("官方发牌员"),
-			}
-			b, _ = gjson.Encode(msg)
-			pai = newpai
-			user.(*ghttp.WebSocket).WriteMessage(ghttp.WS_MSG_TEXT, b)
-
-		}
-	})
-	//paiusers.Clear()
-	return nil
-}
-
-//计算有没有牛
-func niu(num int8) string {
-
-	str := ""
-	switch num {
-	case 0:
-		str = "没有牛"
-	case 10, 11, 12:
-		str = "牛牛"
-	default:
-		str = fmt.Sprintf("牛%d", num)
-	}
-	return str
-}
-
-//获取发牌结果
-func (a *chatApi) ending() (err error) {
-	userpai := []UserPai{}
-	maxu := UserPai{}
-	res := "</br>" //双的牌
-	paiusers.RLockFunc(func(m map[interface{}]interface{}) {
-		//这里加一个定义谁输谁赢,赢多少倍的数据,
-
-		//获取每个用户的点数
-		for user, v := range m {
-			fmt.Println(v)
-			name := gconv.String(v)
-			pai, _ := cache.Get(CachePaiName + name)             //获取缓存中的牌
-			num, maxpai, maxnum := winAndLos(gconv.Strings(pai)) //获取牌中的点数,应该再加一个最大的牌
-			doub := 1
-			switch num {
-			case 7, 8, 9:
-				doub = 2
-			case 10:
-				doub = 3
-			case 11:
-				doub = 5
-			}
-			u := UserPai{
-				Name:     name,
-				Num:      num,
-				Max:      maxpai,
-				Multiple: int8(doub),
-				MaxNum:   maxnum,
-				Pai:      gconv.Strings(pai),
-				User:     user,
-			}
-			fmt.Println("当前最大牌", maxpai, "点位是", maxnum)
-			res += name + ":的牌是---" + strings.Join(gconv.Strings(pai), ",") + fmt.Sprintf("----为:%s", niu(num)) + "</br>"
-			//获取胜负情况
-			if num > maxu.Num || (num == maxu.Num && maxnum > maxu.MaxNum) {
-				maxu = u
-			}
-			userpai = append(userpai, u)
-		}
-	})
-	//开始把两个的牌情况整成数据发送出去
-	for _, v := range userpai {
-		str := res
-		if maxu.Name == v.Name {
-			str += fmt.Sprintf("</br>您赢了%d倍", maxu.Multiple)
-		} else {
-			str += fmt.Sprintf("</br>您输了%d倍", maxu.Multiple)
-		}
-		msg := model.ChatMsg{
-			Type: "send",
-			Data: str,
-			From: ghtml.SpecialChars("官方发牌员"),
-		}
-		b, _ := gjson.Encode(msg)
-		v.User.(*ghttp.WebSocket).WriteMessage(ghttp.WS_MSG_TEXT, b)
-	}
-	//开始计算谁输谁赢,正常只比点数,如果点数一样,那么比牌大小
-	paiusers.Clear()
-	return
-}
-
-//获取牌的点位与最大牌跟最大的点数
-func winAndLos(pai []string) (int8, string, int) {
-	//拿到具体的牌后,开始计算倍数与点数
-	max := ""
-	maxnum := 0
-	jin := 0
-	painum := []int{}
-	num := int8(0)
-	for _, v := range pai {
-		d, dou := dian(v) //获取牌的点数与倍数,计算点与牌大小
-
-		painum = append(painum, d)
-		if dou > maxnum {
-			maxnum = dou //计算最大的点位,
-			max = v
-		}
-		if d > 10 {
-			jin++
-			d = 10
-		}
-
-		num += int8(d)
-	}
-	//五朵金花的
-	if jin == 5 {
-		num = 11
-	} else {
-		//开始正式计算点数
-		num = godian(painum)
-	}
-	return num, max, maxnum
-}
-
-//开始计算是否为点数与牛牛
-func godian(ints []int) int8 {
-	var newints []int
-	//五张牌,第一轮循环,先把花色去掉
-	for _, v := range ints {
-		if v < 10 {
-			newints = append(newints, v)
-		}
-	}
-	//把新的低于10个点的用算法计算出点数
-	//如果剩下一张,那么直接返回一张的点
-
-	//如果有三四五张怎么计算点数
-	return gconv.Int8(word(newints))
-}
-
-//看整个数组计算出来有多少点
-func word(newints []int) int {
-	le := len(newints)
-	switch le {
-	case 1:
-		return newints[0]
-	case 2:
-		n := (newints[0] + newints[1]) % 10
-		if n == 0 {
-			return 10
+//处理房间与牌桌操作,返回只发给自己的回复,不是牌桌操作时返回errNotAction
+func (a *chatApi) action(c *client, name, dd string) (string, error) {
+	cmd, args, ok := parseCommand(dd)
+	if !ok {
+		//不是/开头的命令时,看看是不是中文的快捷输入
+		if cmd, args, ok = parseShortcut(dd); !ok {
+			return "", errNotAction
 		}
-		return n
-	case 3, 4, 5:
-		//如果是有四五张,那么再加一个循环计算
-		in := fourAndFive(newints)
-		if len(in) > 2 {
-			return 0
-		}
-		return word(in)
 	}
-	return 10
-}
-
-//把两两相加,三个相加为10的处理掉
-func fourAndFive(ints []int) []int {
-	//先检查一下有没有两两相加为十的
-	le := len(ints)
-	fmt.Printf("牌的长度为%d", le)
-	for i := 0; i < le-2; i++ {
-		for j := i + 1; j < le-1; j++ {
-			for o := j + 1; o < le; o++ {
-				n := ints[i] + ints[j] + ints[o]
-				if n == 10 || n == 20 {
-					ints = append(ints[:o], ints[o+1:]...)
-					ints = append(ints[:j], ints[j+1:]...)
-					ints = append(ints[:i], ints[i+1:]...)
-					return ints
-				}
-			}
-		}
-	}
-	for i := 0; i < le; i++ {
-		for j := i + 1; j < le; j++ {
-			if ints[i]+ints[j] == 10 {
-				le -= 2
-				ints = append(ints[:j], ints[j+1:]...)
-				ints = append(ints[:i], ints[i+1:]...)
-				i--
-				break
-			}
-		}
-	}
-
-	return ints
-}
-
-//1-10为具体点数,11 12 13分别为jqk为花
-func dian(s string) (d, dou int) {
-	rs := []rune(s)
-	switch string(rs[2:]) {
-	case "A":
-		d = 1
-	case "J":
-		d = 11
-	case "Q":
-		d = 12
-	case "K":
-		d = 13
-	default:
-		d = gconv.Int(string(rs[2:]))
-	}
-
-	switch string(rs[:2]) {
-	case "黑桃":
-		dou = 4
-	case "红桃":
-		dou = 3
-	case "梅花":
-		dou = 2
-	case "方块":
-		dou = 1
-	}
-	dou = dou + d*10
-	return
-}
-
-//发五张牌,并且把剩下的牌返回回去,应该加一个如果剩余15张以后直接按序去发就好了.
-func fapai(pai []string) (uspai []string, newpai []string) {
-	//var uspai []string
-	var num = 1
-	for num <= 5 {
-		//获取牌的长度,得到一个随机数
-		i := ra(len(pai))
-		num++
-		uspai = append(uspai, pai[i])
-		pai = append(pai[:i], pai[i+1:]...)
-		//fmt.Println(num)
-	}
-	newpai = pai
-
-	return
-}
-
-//得到一个随机数,用于获取牌的位置
-func ra(i int) (j int) {
-	rand.Seed(time.Now().UnixNano())
-	j = rand.Intn(i)
-	return
+	return runCommand(&commandCtx{client: c, name: name}, cmd, args)
 }
 
 // 向客户端写入消息。
 // 内部方法不会自动注册到路由中。
-func (a *chatApi) write(ws *ghttp.WebSocket, msg model.ChatMsg) error {
-	msgBytes, err := gjson.Encode(msg)
-	if err != nil {
-		return err
-	}
-	return ws.WriteMessage(ghttp.WS_MSG_TEXT, msgBytes)
+func (a *chatApi) write(c *client, msg model.ChatMsg) error {
+	return c.Send(msg)
 }
 
 // 向所有客户端群发消息。
 // 内部方法不会自动注册到路由中。
 func (a *chatApi) writeGroup(msg model.ChatMsg) error {
-	b, err := gjson.Encode(msg)
+	b, err := gjson.Encode(a.seal(msg))
 	if err != nil {
 		return err
 	}
+	// 只放进每个客户端的发送队列,不会被慢的客户端阻塞
 	users.RLockFunc(func(m map[interface{}]interface{}) {
 		for user := range m {
-			user.(*ghttp.WebSocket).WriteMessage(ghttp.WS_MSG_TEXT, []byte(b))
+			user.(*client).enqueue(b)
 		}
 	})
 
 	return nil
 }
 
+// 给发出的消息加上协议版本、消息ID与发送时间,已经带了时间的消息(例如断线期间错过的消息)保留原来的时间。
+// 内部方法不会自动注册到路由中。
+func (a *chatApi) seal(msg model.ChatMsg) model.ChatMsg {
+	msg.Version = model.ProtocolVersion
+	msg.Id = msgId.Add(1)
+	if msg.Time == 0 {
+		msg.Time = gtime.TimestampMilli()
+	}
+	return msg
+}
+
+// 会话的钱包账号,设置昵称或者打开聊天室页面时生成账号并开户,之后换了昵称也还是同一个钱包。
+// 内部方法不会自动注册到路由中。
+func (a *chatApi) account(r *ghttp.Request) string {
+	account := r.Session.GetString("chat_wallet")
+	if account == "" {
+		account = guid.S()
+		r.Session.Set("chat_wallet", account)
+	}
+	service.Wallet.Open(account)
+	return account
+}
+
+// 是否还有这个昵称的连接。
+// 内部方法不会自动注册到路由中。
+func (a *chatApi) online(name string) bool {
+	found := false
+	users.Iterator(func(k interface{}, v interface{}) bool {
+		found = v.(string) == name
+		return !found
+	})
+	return found
+}
+
 // 向客户端返回用户列表。
 // 内部方法不会自动注册到路由中。
 func (a *chatApi) writeUserListToClient() error {
@@ -553,7 +369,7 @@ func (a *chatApi) writeUserListToClient() error {
 		return true
 	})
 	if err := a.writeGroup(model.ChatMsg{
-		Type: "list",
+		Type: model.MsgList,
 		Data: array.Slice(),
 		From: "",
 	}); err != nil {
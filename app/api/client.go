@@ -0,0 +1,178 @@
+package api
+
+import (
+	"sync"
+	"time"
+
+	"niuniu/app/model"
+
+	"github.com/gogf/gf/container/gtype"
+	"github.com/gogf/gf/encoding/gjson"
+	"github.com/gogf/gf/errors/gerror"
+	"github.com/gogf/gf/frame/g"
+	"github.com/gogf/gf/net/ghttp"
+	"github.com/gogf/gf/os/gtimer"
+	"github.com/gorilla/websocket"
+)
+
+// 发送队列满了时的处理方式
+const (
+	slowDrop  = "drop"  // 丢掉新的消息
+	slowClose = "close" // 断开连接
+)
+
+// 连接已经关闭
+var errClientClosed = gerror.New("连接已经关闭")
+
+// 定时清理没有心跳的客户端,第一个客户端连接时启动
+var reaperOnce sync.Once
+
+// WebSocket客户端,只有一个写协程负责往连接里写消息,其他地方发送消息时只放进发送队列,不会阻塞
+type client struct {
+	ws      *ghttp.WebSocket
+	name    string
+	account string        // 会话的钱包账号
+	send    chan []byte   // 发送队列
+	closed  chan struct{} // 关闭后不再发送消息
+	once    sync.Once
+	dropped int // 发送队列满了丢掉的消息数量
+	mu      sync.Mutex
+	seen    *gtype.Int64 // 最后一次收到消息或者pong的时间,毫秒
+}
+
+// 创建客户端并启动写协程,发送队列的长度、队列满了时的处理方式与心跳时间在config.toml的chat中配置。
+// 超过pongWait没有收到任何消息或者pong的连接会因为读取超时而断开
+func newClient(ws *ghttp.WebSocket, name, account string) *client {
+	c := &client{
+		ws:      ws,
+		name:    name,
+		account: account,
+		send:    make(chan []byte, g.Cfg().GetInt("chat.sendQueue", 64)),
+		closed:  make(chan struct{}),
+		seen:    gtype.NewInt64(time.Now().UnixNano() / int64(time.Millisecond)),
+	}
+	ws.SetReadDeadline(time.Now().Add(pongWait()))
+	ws.SetPongHandler(func(string) error {
+		c.touch()
+		return nil
+	})
+	reaperOnce.Do(func() {
+		gtimer.Add(pingInterval(), reap)
+	})
+	go c.writeLoop()
+	return c
+}
+
+// 收到消息或者pong时延长读取的期限
+func (c *client) touch() {
+	c.seen.Set(time.Now().UnixNano() / int64(time.Millisecond))
+	c.ws.SetReadDeadline(time.Now().Add(pongWait()))
+}
+
+// 是否已经超过pongWait没有心跳
+func (c *client) stale() bool {
+	seen := time.Unix(0, c.seen.Val()*int64(time.Millisecond))
+	return time.Since(seen) > pongWait()
+}
+
+// 发送消息,实现牌桌使用的连接
+func (c *client) Send(msg model.ChatMsg) error {
+	b, err := gjson.Encode(Chat.seal(msg))
+	if err != nil {
+		return err
+	}
+	return c.enqueue(b)
+}
+
+// 钱包账号,实现牌桌使用的连接
+func (c *client) Account() string {
+	return c.account
+}
+
+// 把编码好的消息放进发送队列,队列满了时按配置丢掉消息或者断开连接
+func (c *client) enqueue(b []byte) error {
+	select {
+	case <-c.closed:
+		return errClientClosed
+	default:
+	}
+	select {
+	case c.send <- b:
+		return nil
+	default:
+	}
+	if g.Cfg().GetString("chat.slowClient", slowDrop) == slowClose {
+		g.Log().Warningf("%s的发送队列满了,断开连接", c.name)
+		c.Close()
+		return errClientClosed
+	}
+	c.mu.Lock()
+	c.dropped++
+	dropped := c.dropped
+	c.mu.Unlock()
+	g.Log().Warningf("%s的发送队列满了,已经丢掉%d条消息", c.name, dropped)
+	return nil
+}
+
+// 关闭连接,读协程会因为读取失败而退出并清理用户信息
+func (c *client) Close() {
+	c.once.Do(func() {
+		close(c.closed)
+		c.ws.Close()
+	})
+}
+
+// 写协程,按顺序把发送队列里的消息写到连接里,定时发送ping,写失败或者写超时时关闭连接
+func (c *client) writeLoop() {
+	ticker := time.NewTicker(pingInterval())
+	defer ticker.Stop()
+	for {
+		select {
+		case b := <-c.send:
+			c.ws.SetWriteDeadline(time.Now().Add(writeWait()))
+			if err := c.ws.WriteMessage(ghttp.WS_MSG_TEXT, b); err != nil {
+				c.Close()
+				return
+			}
+		case <-ticker.C:
+			c.ws.SetWriteDeadline(time.Now().Add(writeWait()))
+			if err := c.ws.WriteMessage(websocket.PingMessage, nil); err != nil {
+				c.Close()
+				return
+			}
+		case <-c.closed:
+			return
+		}
+	}
+}
+
+// 清理没有心跳的客户端,关闭连接后读协程会清理用户信息并通知所在的牌桌
+func reap() {
+	stale := []*client{}
+	users.RLockFunc(func(m map[interface{}]interface{}) {
+		for user := range m {
+			if c := user.(*client); c.stale() {
+				stale = append(stale, c)
+			}
+		}
+	})
+	for _, c := range stale {
+		g.Log().Infof("%s已经%v没有心跳,断开连接", c.name, pongWait())
+		c.Close()
+	}
+}
+
+// 发送ping的间隔
+func pingInterval() time.Duration {
+	return time.Duration(g.Cfg().GetInt("chat.pingInterval", 20)) * time.Second
+}
+
+// 多久没有收到消息或者pong就断开连接,要比pingInterval长
+func pongWait() time.Duration {
+	return time.Duration(g.Cfg().GetInt("chat.pongWait", 60)) * time.Second
+}
+
+// 写一条消息的超时时间
+func writeWait() time.Duration {
+	return time.Duration(g.Cfg().GetInt("chat.writeWait", 10)) * time.Second
+}
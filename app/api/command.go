@@ -0,0 +1,427 @@
+package api
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"niuniu/app/model"
+	"niuniu/app/service"
+
+	"github.com/gogf/gf/errors/gerror"
+	"github.com/gogf/gf/frame/g"
+)
+
+// 聊天框里输入的命令,以/开头,例如/bet 3
+type command struct {
+	name string // 命令名称
+	args string // 参数说明,用于提示用法
+	desc string // 命令说明
+	min  int    // 最少几个参数
+	max  int    // 最多几个参数
+	run  func(c *commandCtx, args []string) (string, error)
+}
+
+// 执行命令时的上下文
+type commandCtx struct {
+	client *client
+	name   string
+}
+
+var (
+	commands     = map[string]*command{} // 命令名称对应的命令
+	commandNames []string                // 按注册顺序排列的命令名称,用于/help
+)
+
+func init() {
+	registerCommand(&command{name: "help", desc: "查看所有命令", run: cmdHelp})
+	registerCommand(&command{name: "rooms", desc: "查看房间列表", run: cmdRooms})
+	registerCommand(&command{name: "create", args: "[规则名]", desc: "创建房间", max: 1, run: cmdCreate})
+	registerCommand(&command{name: "join", args: "[房间号]", desc: "加入房间,不带房间号时快速加入并准备", max: 1, run: cmdJoin})
+	registerCommand(&command{name: "code", args: "房号 [密码]", desc: "通过房号加入私人房间", min: 1, max: 2, run: cmdCode})
+	registerCommand(&command{name: "watch", args: "房间号", desc: "观战", min: 1, max: 1, run: cmdWatch})
+	registerCommand(&command{name: "leave", desc: "离开房间", run: cmdLeave})
+	registerCommand(&command{name: "sit", args: "[座位号]", desc: "坐下,不带座位号时坐到第一个空座位", max: 1, run: cmdSit})
+	registerCommand(&command{name: "stand", desc: "站起,让出座位", run: cmdStand})
+	registerCommand(&command{name: "ready", desc: "准备", run: cmdReady})
+	registerCommand(&command{name: "unready", desc: "取消准备", run: cmdUnready})
+	registerCommand(&command{name: "grab", args: "倍数", desc: "抢庄,0为不抢", min: 1, max: 1, run: cmdGrab})
+	registerCommand(&command{name: "bet", args: "分数", desc: "下注", min: 1, max: 1, run: cmdBet})
+	registerCommand(&command{name: "reveal", desc: "亮牌", run: cmdReveal})
+	registerCommand(&command{name: "rules", desc: "查看当前房间的规则,不在房间里时查看所有规则", run: cmdRules})
+	registerCommand(&command{name: "score", desc: "查看本房间的战绩", run: cmdScore})
+	registerCommand(&command{name: "balance", desc: "查看自己的筹码余额", run: cmdBalance})
+	registerCommand(&command{name: "dissolve", desc: "申请解散房间", run: cmdDissolve})
+	registerCommand(&command{name: "agree", desc: "同意解散房间", run: cmdAgree})
+	registerCommand(&command{name: "refuse", desc: "拒绝解散房间", run: cmdRefuse})
+}
+
+// 注册命令,同名的命令后注册的覆盖先注册的
+func registerCommand(cmd *command) {
+	if _, ok := commands[cmd.name]; !ok {
+		commandNames = append(commandNames, cmd.name)
+	}
+	commands[cmd.name] = cmd
+}
+
+// 解析/开头的命令,返回命令名称与参数
+func parseCommand(s string) (string, []string, bool) {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "/") {
+		return "", nil, false
+	}
+	fields := strings.Fields(strings.TrimPrefix(s, "/"))
+	if len(fields) == 0 {
+		return "", nil, true
+	}
+	return strings.ToLower(fields[0]), fields[1:], true
+}
+
+// 执行命令,参数个数不对时返回用法
+func runCommand(c *commandCtx, name string, args []string) (string, error) {
+	cmd, ok := commands[name]
+	if !ok {
+		return "", gerror.Newf("未知命令/%s,输入/help查看所有命令", name)
+	}
+	if len(args) < cmd.min || len(args) > cmd.max {
+		return "", cmd.usage()
+	}
+	return cmd.run(c, args)
+}
+
+// 命令的用法
+func (cmd *command) usage() error {
+	if cmd.args == "" {
+		return gerror.Newf("用法:/%s", cmd.name)
+	}
+	return gerror.Newf("用法:/%s %s", cmd.name, cmd.args)
+}
+
+// 把第i个参数解析成整数,不是整数时返回用法
+func (c *commandCtx) int(name string, args []string, i int) (int, error) {
+	n, err := strconv.Atoi(args[i])
+	if err != nil {
+		return 0, commands[name].usage()
+	}
+	return n, nil
+}
+
+// 玩家所在的房间
+func (c *commandCtx) table() (*service.Table, error) {
+	t := service.Room.Of(c.name)
+	if t == nil {
+		return nil, gerror.New("您不在房间里,输入/join快速加入")
+	}
+	return t, nil
+}
+
+func (c *commandCtx) conn() service.Conn {
+	return c.client
+}
+
+// 把结构化的消息只发给自己,文字说明由命令的返回值发出
+func (c *commandCtx) notify(typ string, data interface{}) {
+	if err := c.client.Send(model.ChatMsg{Type: typ, Data: data, From: service.DealerName}); err != nil {
+		g.Log().Error(err)
+	}
+}
+
+func cmdHelp(c *commandCtx, args []string) (string, error) {
+	infos := make([]model.CommandInfo, len(commandNames))
+	items := make([]string, len(commandNames))
+	for i, name := range commandNames {
+		cmd := commands[name]
+		infos[i] = model.CommandInfo{Name: cmd.name, Args: cmd.args, Desc: cmd.desc}
+		items[i] = strings.TrimSpace("/"+cmd.name+" "+cmd.args) + ":" + cmd.desc
+	}
+	c.notify(model.MsgHelp, infos)
+	return strings.Join(items, "</br>"), nil
+}
+
+func cmdRooms(c *commandCtx, args []string) (string, error) {
+	return service.Room.Describe(), nil
+}
+
+func cmdCreate(c *commandCtx, args []string) (string, error) {
+	rule := ""
+	if len(args) > 0 {
+		rule = args[0]
+	}
+	t, err := service.Room.Open(rule, model.RoomOption{}, c.name, c.conn())
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("您创建了房间%d,房号%s", t.ID(), t.Code()), nil
+}
+
+func cmdJoin(c *commandCtx, args []string) (string, error) {
+	if len(args) == 0 {
+		//快速加入一个房间并准备,准备的人数够了之后自动开局
+		t, err := service.Room.Quick(c.name, c.conn())
+		if err != nil {
+			return "", err
+		}
+		if err := t.Ready(c.name, true); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("您进入了房间%d", t.ID()), nil
+	}
+	id, err := c.int("join", args, 0)
+	if err != nil {
+		return "", err
+	}
+	t, err := service.Room.Join(id, c.name, c.conn())
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("您进入了房间%d", t.ID()), nil
+}
+
+func cmdCode(c *commandCtx, args []string) (string, error) {
+	passcode := ""
+	if len(args) > 1 {
+		passcode = args[1]
+	}
+	t, err := service.Room.JoinCode(args[0], passcode, c.name, c.conn())
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("您进入了房间%d", t.ID()), nil
+}
+
+func cmdWatch(c *commandCtx, args []string) (string, error) {
+	id, err := c.int("watch", args, 0)
+	if err != nil {
+		return "", err
+	}
+	t, err := service.Room.Watch(id, c.name, c.conn())
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("您进入了房间%d观战,输入/sit可以坐下", t.ID()), nil
+}
+
+func cmdLeave(c *commandCtx, args []string) (string, error) {
+	if err := service.Room.Leave(c.name); err != nil {
+		return "", err
+	}
+	return "您离开了房间", nil
+}
+
+func cmdSit(c *commandCtx, args []string) (string, error) {
+	seat := 0
+	if len(args) > 0 {
+		n, err := c.int("sit", args, 0)
+		if err != nil {
+			return "", err
+		}
+		seat = n
+	}
+	t, err := c.table()
+	if err != nil {
+		return "", err
+	}
+	return "", t.Sit(c.name, seat)
+}
+
+func cmdStand(c *commandCtx, args []string) (string, error) {
+	t, err := c.table()
+	if err != nil {
+		return "", err
+	}
+	return "", t.Stand(c.name)
+}
+
+func cmdReady(c *commandCtx, args []string) (string, error) {
+	t, err := c.table()
+	if err != nil {
+		return "", err
+	}
+	return "", t.Ready(c.name, true)
+}
+
+func cmdUnready(c *commandCtx, args []string) (string, error) {
+	t, err := c.table()
+	if err != nil {
+		return "", err
+	}
+	return "", t.Ready(c.name, false)
+}
+
+func cmdGrab(c *commandCtx, args []string) (string, error) {
+	n, err := c.int("grab", args, 0)
+	if err != nil {
+		return "", err
+	}
+	t, err := c.table()
+	if err != nil {
+		return "", err
+	}
+	return "", t.Grab(c.name, n)
+}
+
+func cmdBet(c *commandCtx, args []string) (string, error) {
+	n, err := c.int("bet", args, 0)
+	if err != nil {
+		return "", err
+	}
+	t, err := c.table()
+	if err != nil {
+		return "", err
+	}
+	return "", t.Bet(c.name, n)
+}
+
+func cmdReveal(c *commandCtx, args []string) (string, error) {
+	t, err := c.table()
+	if err != nil {
+		return "", err
+	}
+	return "", t.Reveal(c.name)
+}
+
+func cmdRules(c *commandCtx, args []string) (string, error) {
+	if t := service.Room.Of(c.name); t != nil {
+		c.notify(model.MsgRules, service.Rule.Infos([]*model.RuleSet{t.Rule()}))
+		return service.Rule.Describe(t.Rule()), nil
+	}
+	list, err := service.Rule.List()
+	if err != nil {
+		return "", err
+	}
+	infos := service.Rule.Infos(list)
+	items := make([]string, len(infos))
+	for i, v := range infos {
+		items[i] = v.Name + ":" + v.Desc
+	}
+	c.notify(model.MsgRules, infos)
+	return strings.Join(items, "</br>"), nil
+}
+
+func cmdScore(c *commandCtx, args []string) (string, error) {
+	t, err := c.table()
+	if err != nil {
+		return "", err
+	}
+	c.notify(model.MsgSummary, t.Summary())
+	return t.Score(), nil
+}
+
+func cmdBalance(c *commandCtx, args []string) (string, error) {
+	balance, err := service.Wallet.Balance(c.client.Account())
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("您的余额为%d筹码,钱包账号%s", balance, c.client.Account()), nil
+}
+
+func cmdDissolve(c *commandCtx, args []string) (string, error) {
+	t, err := c.table()
+	if err != nil {
+		return "", err
+	}
+	return "", t.Dissolve(c.name)
+}
+
+func cmdAgree(c *commandCtx, args []string) (string, error) {
+	t, err := c.table()
+	if err != nil {
+		return "", err
+	}
+	return "", t.Vote(c.name, true)
+}
+
+func cmdRefuse(c *commandCtx, args []string) (string, error) {
+	t, err := c.table()
+	if err != nil {
+		return "", err
+	}
+	return "", t.Vote(c.name, false)
+}
+
+// 中文快捷输入对应的命令,完全一致才算
+var shortcuts = map[string]string{
+	"111":  "join",
+	"房间":   "rooms",
+	"离开":   "leave",
+	"站起":   "stand",
+	"准备":   "ready",
+	"取消准备": "unready",
+	"亮牌":   "reveal",
+	"结果":   "reveal",
+	"结束":   "reveal",
+	"战绩":   "score",
+	"余额":   "balance",
+	"解散":   "dissolve",
+	"同意":   "agree",
+	"拒绝":   "refuse",
+	"坐下":   "sit",
+}
+
+// 中文快捷输入中带数字的前缀对应的命令,例如"下注3"、"抢2"、"加入1"
+var numberShortcuts = []struct {
+	prefix string
+	name   string
+}{
+	{"下注", "bet"},
+	{"抢", "grab"},
+	{"加入", "join"},
+	{"观战", "watch"},
+	{"坐下", "sit"},
+}
+
+// 解析中文的快捷输入,返回对应的命令与参数,不是快捷输入时当作聊天内容
+func parseShortcut(s string) (string, []string, bool) {
+	if name, ok := shortcuts[s]; ok {
+		return name, nil, true
+	}
+	if s == "不抢" {
+		return "grab", []string{"0"}, true
+	}
+	for _, v := range numberShortcuts {
+		if !strings.HasPrefix(s, v.prefix) {
+			continue
+		}
+		arg := strings.TrimPrefix(s, v.prefix)
+		if _, err := strconv.Atoi(arg); err == nil {
+			return v.name, []string{arg}, true
+		}
+	}
+	//"建房"或"建房 规则名",规则名必须存在,否则当作聊天内容,例如"建房间吧"
+	if s == "建房" {
+		return "create", nil, true
+	}
+	if rest := strings.TrimPrefix(s, "建房"); rest != s && startsWithSpace(rest) {
+		if fields := strings.Fields(rest); len(fields) == 1 {
+			if _, err := service.Rule.Get(fields[0]); err == nil {
+				return "create", fields, true
+			}
+		}
+	}
+	//"房号N"或"房号N 密码",房号必须是数字,否则当作聊天内容,例如"房号是多少"
+	if rest := strings.TrimPrefix(s, "房号"); rest != s {
+		if fields := strings.Fields(rest); (len(fields) == 1 || len(fields) == 2) && isDigits(fields[0]) {
+			return "code", fields, true
+		}
+	}
+	return "", nil, false
+}
+
+// 是否以空白字符开头
+func startsWithSpace(s string) bool {
+	return s != "" && strings.TrimLeftFunc(s, unicode.IsSpace) != s
+}
+
+// 是否全是数字
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
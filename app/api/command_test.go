@@ -0,0 +1,71 @@
+package api
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseCommand(t *testing.T) {
+	cases := []struct {
+		in   string
+		name string
+		args []string
+		ok   bool
+	}{
+		{"/join", "join", []string{}, true},
+		{" /BET  3 ", "bet", []string{"3"}, true},
+		{"/code 123456 abc", "code", []string{"123456", "abc"}, true},
+		{"/", "", nil, true},
+		{"下注3", "", nil, false},
+		{"你好", "", nil, false},
+	}
+	for _, c := range cases {
+		name, args, ok := parseCommand(c.in)
+		if name != c.name || ok != c.ok || !reflect.DeepEqual(args, c.args) {
+			t.Errorf("%q: %q %q %v,应该是%q %q %v", c.in, name, args, ok, c.name, c.args, c.ok)
+		}
+	}
+}
+
+func TestParseShortcut(t *testing.T) {
+	cases := []struct {
+		in   string
+		name string
+		args []string
+		ok   bool
+	}{
+		// 完全一致的快捷输入
+		{"111", "join", nil, true},
+		{"准备", "ready", nil, true},
+		{"不抢", "grab", []string{"0"}, true},
+		// 带数字的前缀
+		{"下注3", "bet", []string{"3"}, true},
+		{"抢2", "grab", []string{"2"}, true},
+		{"加入1", "join", []string{"1"}, true},
+		{"坐下2", "sit", []string{"2"}, true},
+		{"下注吧", "", nil, false},
+		// 建房只在后面没有内容或者空格加存在的规则名时才是命令
+		{"建房", "create", nil, true},
+		{"建房 classic", "create", []string{"classic"}, true},
+		{"建房classic", "", nil, false},
+		{"建房间吧", "", nil, false},
+		{"建房 好吗", "", nil, false},
+		{"建房 classic 吧", "", nil, false},
+		// 房号后面必须是数字
+		{"房号123456", "code", []string{"123456"}, true},
+		{"房号 123456 abc", "code", []string{"123456", "abc"}, true},
+		{"房号", "", nil, false},
+		{"房号是多少", "", nil, false},
+		{"房号123是多少", "", nil, false},
+		{"房号 1 2 3", "", nil, false},
+		// 其他内容都是聊天
+		{"准备好了吗", "", nil, false},
+		{"你好", "", nil, false},
+	}
+	for _, c := range cases {
+		name, args, ok := parseShortcut(c.in)
+		if name != c.name || ok != c.ok || !reflect.DeepEqual(args, c.args) {
+			t.Errorf("%q: %q %q %v,应该是%q %q %v", c.in, name, args, ok, c.name, c.args, c.ok)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package api
+
+import (
+	"niuniu/app/model"
+	"niuniu/app/service"
+	"niuniu/library/response"
+
+	"github.com/gogf/gf/errors/gerror"
+	"github.com/gogf/gf/frame/g"
+	"github.com/gogf/gf/net/ghttp"
+)
+
+// 房间API管理对象
+var Room = &roomApi{}
+
+type roomApi struct{}
+
+// @summary 房间列表接口
+// @description 返回公开的房间列表,私人房间不显示。
+// @tags    房间
+// @produce json
+// @router  /room/list [GET]
+// @success 200 {object} response.JsonResponse "执行结果"
+func (a *roomApi) List(r *ghttp.Request) {
+	response.JsonExit(r, 0, "ok", service.Room.List())
+}
+
+// @summary 规则列表接口
+// @description 返回可以选择的规则,创建房间时通过规则名称选择。
+// @tags    房间
+// @produce json
+// @router  /room/rules [GET]
+// @success 200 {object} response.JsonResponse "执行结果"
+func (a *roomApi) Rules(r *ghttp.Request) {
+	list, err := service.Rule.List()
+	if err != nil {
+		response.JsonExit(r, 1, err.Error())
+	}
+	response.JsonExit(r, 0, "ok", service.Rule.Infos(list))
+}
+
+// @summary 创建私人房间接口
+// @description 创建一个私人房间,返回房间信息,其他玩家通过房号加入,设置了密码时需要带上密码。
+// @tags    房间
+// @produce json
+// @param   entity  body model.RoomApiCreateReq true "创建请求"
+// @router  /room/create [POST]
+// @success 200 {object} response.JsonResponse "执行结果"
+func (a *roomApi) Create(r *ghttp.Request) {
+	var (
+		apiReq *model.RoomApiCreateReq
+	)
+	if err := r.Parse(&apiReq); err != nil {
+		response.JsonExit(r, 1, gerror.Current(err).Error())
+	}
+	t, err := service.Room.Create(apiReq.Rule, model.RoomOption{
+		Rounds:   apiReq.Rounds,
+		Passcode: apiReq.Passcode,
+		Private:  true,
+		Vote:     apiReq.Vote,
+		Seats:    apiReq.Seats,
+		Watchers: apiReq.Watchers,
+		Delay:    apiReq.Delay,
+	})
+	if err != nil {
+		response.JsonExit(r, 1, err.Error())
+	}
+	response.JsonExit(r, 0, "ok", t.Info())
+}
+
+// @summary 取消一局接口
+// @description 管理员取消房间正在进行的一局,下注全部退回,本局不计分,需要带上config.toml中admin.token配置的口令,没有配置口令时不能使用。
+// @tags    房间
+// @produce json
+// @param   entity  body model.RoomApiAbortReq true "取消请求"
+// @router  /room/abort [POST]
+// @success 200 {object} response.JsonResponse "执行结果"
+func (a *roomApi) Abort(r *ghttp.Request) {
+	var (
+		apiReq *model.RoomApiAbortReq
+	)
+	if err := r.Parse(&apiReq); err != nil {
+		response.JsonExit(r, 1, gerror.Current(err).Error())
+	}
+	token := g.Cfg().GetString("admin.token")
+	if token == "" || apiReq.Token != token {
+		response.JsonExit(r, 1, "管理员口令不正确")
+	}
+	t, err := service.Room.Code(apiReq.Code)
+	if err != nil {
+		response.JsonExit(r, 1, err.Error())
+	}
+	if err := t.Abort(apiReq.Reason); err != nil {
+		response.JsonExit(r, 1, err.Error())
+	}
+	response.JsonExit(r, 0, "ok", t.Info())
+}
+
+// @summary 房间信息接口
+// @description 通过房号查询房间信息。
+// @tags    房间
+// @produce json
+// @param   code query string true "房号"
+// @router  /room/info [GET]
+// @success 200 {object} response.JsonResponse "执行结果"
+func (a *roomApi) Info(r *ghttp.Request) {
+	var (
+		apiReq *model.RoomApiCodeReq
+	)
+	if err := r.Parse(&apiReq); err != nil {
+		response.JsonExit(r, 1, gerror.Current(err).Error())
+	}
+	t, err := service.Room.Code(apiReq.Code)
+	if err != nil {
+		response.JsonExit(r, 1, err.Error())
+	}
+	response.JsonExit(r, 0, "ok", t.Info())
+}
+
+// @summary 房间战绩接口
+// @description 通过房号查询房间的战绩汇总,包括每个玩家的总分、赢的局数、坐庄次数、牛牛次数与最大的一手牌。
+// @tags    房间
+// @produce json
+// @param   code query string true "房号"
+// @router  /room/score [GET]
+// @success 200 {object} response.JsonResponse "执行结果"
+func (a *roomApi) Score(r *ghttp.Request) {
+	var (
+		apiReq *model.RoomApiCodeReq
+	)
+	if err := r.Parse(&apiReq); err != nil {
+		response.JsonExit(r, 1, gerror.Current(err).Error())
+	}
+	t, err := service.Room.Code(apiReq.Code)
+	if err != nil {
+		response.JsonExit(r, 1, err.Error())
+	}
+	response.JsonExit(r, 0, "ok", t.Summary())
+}
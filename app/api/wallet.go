@@ -0,0 +1,82 @@
+package api
+
+import (
+	"niuniu/app/model"
+	"niuniu/app/service"
+	"niuniu/library/response"
+
+	"github.com/gogf/gf/errors/gerror"
+	"github.com/gogf/gf/frame/g"
+	"github.com/gogf/gf/net/ghttp"
+)
+
+// 筹码钱包API管理对象
+var Wallet = &walletApi{}
+
+type walletApi struct{}
+
+// @summary 余额查询接口
+// @description 查询当前会话的筹码余额,进入聊天室时才会开户,查询不会开户。
+// @tags    钱包
+// @produce json
+// @router  /wallet/balance [GET]
+// @success 200 {object} response.JsonResponse "执行结果"
+func (a *walletApi) Balance(r *ghttp.Request) {
+	account := r.Session.GetString("chat_wallet")
+	if account == "" {
+		response.JsonExit(r, 1, "还没有钱包,请先进入聊天室")
+	}
+	balance, err := service.Wallet.Balance(account)
+	if err != nil {
+		response.JsonExit(r, 1, err.Error())
+	}
+	response.JsonExit(r, 0, "ok", model.WalletInfo{Account: account, Balance: balance})
+}
+
+// @summary 增加筹码接口
+// @description 管理员给玩家增加筹码,需要带上config.toml中admin.token配置的口令。
+// @tags    钱包
+// @produce json
+// @param   entity  body model.WalletApiChangeReq true "增加请求"
+// @router  /wallet/credit [POST]
+// @success 200 {object} response.JsonResponse "执行结果"
+func (a *walletApi) Credit(r *ghttp.Request) {
+	apiReq := a.change(r)
+	balance, err := service.Wallet.Credit(apiReq.Account, apiReq.Amount)
+	if err != nil {
+		response.JsonExit(r, 1, err.Error())
+	}
+	response.JsonExit(r, 0, "ok", model.WalletInfo{Account: apiReq.Account, Balance: balance})
+}
+
+// @summary 扣除筹码接口
+// @description 管理员扣除玩家的筹码,余额不足时不扣,需要带上config.toml中admin.token配置的口令。
+// @tags    钱包
+// @produce json
+// @param   entity  body model.WalletApiChangeReq true "扣除请求"
+// @router  /wallet/debit [POST]
+// @success 200 {object} response.JsonResponse "执行结果"
+func (a *walletApi) Debit(r *ghttp.Request) {
+	apiReq := a.change(r)
+	balance, err := service.Wallet.Debit(apiReq.Account, apiReq.Amount)
+	if err != nil {
+		response.JsonExit(r, 1, err.Error())
+	}
+	response.JsonExit(r, 0, "ok", model.WalletInfo{Account: apiReq.Account, Balance: balance})
+}
+
+// 解析增加或者扣除筹码的请求并校验管理员口令。
+// 内部方法不会自动注册到路由中。
+func (a *walletApi) change(r *ghttp.Request) *model.WalletApiChangeReq {
+	var (
+		apiReq *model.WalletApiChangeReq
+	)
+	if err := r.Parse(&apiReq); err != nil {
+		response.JsonExit(r, 1, gerror.Current(err).Error())
+	}
+	token := g.Cfg().GetString("admin.token")
+	if token == "" || apiReq.Token != token {
+		response.JsonExit(r, 1, "管理员口令不正确")
+	}
+	return apiReq
+}
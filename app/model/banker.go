@@ -0,0 +1,26 @@
+package model
+
+// 上庄方式
+const (
+	BankerFixed  = "fixed"  // 固定房主坐庄
+	BankerRotate = "rotate" // 按入座顺序轮流坐庄
+	BankerRandom = "random" // 每局随机坐庄
+	BankerBull   = "bull"   // 牛牛上庄,上一局拿到牛牛及以上牌型的玩家坐庄
+	BankerGrab   = "grab"   // 抢庄,出价最高的坐庄,一样高的随机
+)
+
+// 选庄需要的牌桌信息
+type BankerSelect struct {
+	Mode    string         // 上庄方式
+	Players []string       // 本局玩家,按入座顺序排列
+	Owner   string         // 房主
+	Last    string         // 上一局的庄家
+	History []SettleItem   // 上一局的结算结果,牛牛上庄使用
+	Bids    map[string]int // 抢庄出价,0为不抢
+}
+
+// 选庄结果
+type BankerResult struct {
+	Name     string `json:"name"`     // 庄家昵称
+	Multiple int    `json:"multiple"` // 抢庄倍数,不是抢庄时为1
+}
@@ -1,10 +1,16 @@
 package model
 
-// Chat Msg 消息结构体
+// Chat Msg 消息结构体,也是WebSocket协议的信封,服务端发出的消息都带有协议版本与消息ID,
+// 牌桌发出的消息还带有房间编号与序号,同一个房间里发给一个玩家的消息从1开始连续编号
 type ChatMsg struct {
-	Type string      `json:"type" v:"required#消息类型不能为空"`
-	Data interface{} `json:"data" v:""`
-	From string      `json:"name" v:""`
+	Version int         `json:"v" v:""`
+	Id      int64       `json:"id" v:""`
+	Room    int         `json:"room,omitempty" v:""`
+	Seq     int64       `json:"seq,omitempty" v:""`
+	Type    string      `json:"type" v:"required#消息类型不能为空"`
+	Data    interface{} `json:"data" v:""`
+	From    string      `json:"name" v:""`
+	Time    int64       `json:"time" v:""`
 }
 
 // 设置昵称请求
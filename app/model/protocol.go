@@ -0,0 +1,131 @@
+package model
+
+import (
+	"niuniu/library/card"
+	"niuniu/library/niu"
+)
+
+// WebSocket协议版本,消息结构不兼容时加1
+const ProtocolVersion = 1
+
+// 消息类型
+const (
+	MsgSend         = "send"          // 聊天消息与发牌员的文字提示,data为字符串
+	MsgList         = "list"          // 在线用户列表,data为字符串数组
+	MsgError        = "error"         // 错误提示,data为字符串
+	MsgPing         = "ping"          // 客户端的心跳,服务端回复pong
+	MsgPong         = "pong"          // 服务端对心跳的回复
+	MsgResume       = "resume"        // 客户端发现序号不连续时请求补发,data为收到的最后一个序号
+	MsgDealt        = "dealt"         // 发牌,只发给本人,data为DealtPayload
+	MsgBankerChosen = "banker_chosen" // 选出了庄家,data为BankerChosenPayload
+	MsgBetPlaced    = "bet_placed"    // 闲家下注结束,data为BetPlacedPayload
+	MsgReveal       = "reveal"        // 玩家亮牌,data为RevealPayload
+	MsgSettlement   = "settlement"    // 结算,data为SettlementPayload
+	MsgAbort        = "abort"         // 本局取消了,下注全部退回,data为AbortPayload
+	MsgPhase        = "phase"         // 牌桌进入了有倒计时的阶段,data为PhasePayload
+	MsgSnapshot     = "snapshot"      // 牌桌的快照,断线重连或者没法补发时只发给本人,data为SnapshotPayload
+	MsgSummary      = "summary"       // 整场牌局的战绩,与战绩的文字说明一起发出,data为SessionSummary
+	MsgHelp         = "help"          // 所有命令,与/help的文字说明一起发给本人,data为CommandInfo数组
+	MsgRules        = "rules"         // 规则,与/rules的文字说明一起发给本人,data为RuleInfo数组
+)
+
+// 发牌消息的内容
+type DealtPayload struct {
+	Room     int         `json:"room"`     // 房间编号
+	Round    int         `json:"round"`    // 第几局
+	Cards    []card.Card `json:"cards"`    // 手上所有的牌
+	New      []card.Card `json:"new"`      // 这次新发的牌
+	Complete bool        `json:"complete"` // 是否已经发满五张
+	Hand     *niu.Result `json:"hand"`     // 发满五张时的牌型
+	Title    string      `json:"title"`    // 牌型的中文名
+}
+
+// 选出庄家消息的内容
+type BankerChosenPayload struct {
+	Room     int    `json:"room"`
+	Round    int    `json:"round"`
+	Name     string `json:"name"`     // 庄家
+	Mode     string `json:"mode"`     // 上庄方式
+	Multiple int    `json:"multiple"` // 抢庄倍数
+}
+
+// 一个闲家的下注
+type BetItem struct {
+	Name string `json:"name"`
+	Bet  int    `json:"bet"`
+	Push bool   `json:"push"` // 是否为推注
+}
+
+// 闲家下注消息的内容
+type BetPlacedPayload struct {
+	Room  int       `json:"room"`
+	Round int       `json:"round"`
+	Bets  []BetItem `json:"bets"`
+}
+
+// 亮牌消息的内容
+type RevealPayload struct {
+	Room  int        `json:"room"`
+	Round int        `json:"round"`
+	Name  string     `json:"name"`
+	Hand  niu.Result `json:"hand"`
+	Title string     `json:"title"` // 牌型的中文名
+}
+
+// 结算消息的内容
+type SettlementPayload struct {
+	Room   int              `json:"room"`
+	Round  int              `json:"round"`
+	Banker string           `json:"banker"` // 庄家,通吃模式为空
+	Items  []SettlementItem `json:"items"`
+}
+
+// 取消本局消息的内容
+type AbortPayload struct {
+	Room    int       `json:"room"`
+	Round   int       `json:"round"`
+	Reason  string    `json:"reason"`  // 取消的原因
+	Refunds []BetItem `json:"refunds"` // 退回的下注,还没有下注时为空
+}
+
+// 阶段倒计时消息的内容
+type PhasePayload struct {
+	Room   int    `json:"room"`
+	Round  int    `json:"round"`
+	Phase  string `json:"phase"`  // 当前阶段
+	Remain int64  `json:"remain"` // 当前阶段剩余的毫秒数,没有倒计时为0
+}
+
+// 牌桌快照的内容,断线重连的玩家先收到断线期间错过的消息再收到快照,请求补发时已经没法补发也会收到快照
+type SnapshotPayload struct {
+	Room     RoomInfo        `json:"room"`     // 房间信息,包括阶段、座位与局数
+	Owner    string          `json:"owner"`    // 房主
+	Banker   string          `json:"banker"`   // 本局庄家,还没有选出或者通吃模式时为空
+	Multiple int             `json:"multiple"` // 抢庄倍数
+	Bets     []BetItem       `json:"bets"`     // 闲家的下注,下注结束之前为空
+	Cards    []card.Card     `json:"cards"`    // 自己手上的牌,不在本局玩家中时为空
+	Hand     *niu.Result     `json:"hand"`     // 发满五张时自己的牌型
+	Title    string          `json:"title"`    // 自己牌型的中文名
+	Reveals  []RevealPayload `json:"reveals"`  // 已经亮牌的玩家
+}
+
+// 一个命令的说明
+type CommandInfo struct {
+	Name string `json:"name"` // 命令名称,不带/
+	Args string `json:"args"` // 参数说明
+	Desc string `json:"desc"` // 命令说明
+}
+
+// 一个规则的说明
+type RuleInfo struct {
+	Name  string `json:"name"`  // 规则名称,创建房间时使用
+	Title string `json:"title"` // 显示给玩家的名称
+	Desc  string `json:"desc"`  // 规则的文字说明
+}
+
+// 一个玩家的结算结果
+type SettlementItem struct {
+	SettleItem
+	Title   string `json:"title"`   // 牌型的中文名
+	Balance int    `json:"balance"` // 结算之后的筹码余额
+}
@@ -0,0 +1,67 @@
+package model
+
+// 解散房间的投票方式
+const (
+	VoteMajority  = "majority"  // 过半数同意
+	VoteUnanimous = "unanimous" // 所有人同意
+)
+
+// 房间设置
+type RoomOption struct {
+	Rounds   int    // 局数,0为不限局数
+	Passcode string // 进房密码,为空时不需要密码
+	Private  bool   // 私人房间不在房间列表中显示,只能通过房号加入
+	Vote     string // 解散房间的投票方式,默认过半数同意
+	Seats    int    // 座位数,2到10个,0为默认
+	Watchers int    // 最多几个人观战,0为默认
+	Delay    int    // 观战延迟的秒数,0为不延迟
+}
+
+// 座位信息
+type Seat struct {
+	No      int    `json:"no"`      // 座位号,从1开始
+	Name    string `json:"name"`    // 坐在这里的玩家,为空时是空座位
+	Ready   bool   `json:"ready"`   // 是否已经准备
+	Playing bool   `json:"playing"` // 是否在本局玩家中
+}
+
+// 房间信息,用于房间列表
+type RoomInfo struct {
+	Id      int      `json:"id"`      // 房间编号
+	Code    string   `json:"code"`    // 房号
+	Rule    string   `json:"rule"`    // 规则名称
+	Title   string   `json:"title"`   // 规则显示名称
+	Phase   string   `json:"phase"`   // 牌桌当前阶段
+	Players []string `json:"players"` // 房间里的玩家
+	Seats   []Seat   `json:"seats"`   // 座位
+	Watch   []string `json:"watch"`   // 观战的玩家
+	Max     int      `json:"max"`     // 座位数
+	Private bool     `json:"private"` // 是否为私人房间
+	Locked  bool     `json:"locked"`  // 是否需要密码
+	Rounds  int      `json:"rounds"`  // 局数,0为不限局数
+	Round   int      `json:"round"`   // 已经开了几局
+	Remain  int64    `json:"remain"`  // 当前阶段剩余的毫秒数,没有倒计时为0
+}
+
+// 创建房间请求参数,用于前后端交互参数格式约定
+type RoomApiCreateReq struct {
+	Rule     string
+	Rounds   int    `v:"min:0|max:100#局数不能小于0|局数最多为100局"`
+	Passcode string `v:"max-length:16#密码最长为16位"`
+	Vote     string `v:"in:majority,unanimous#投票方式只能是majority或unanimous"`
+	Seats    int
+	Watchers int
+	Delay    int
+}
+
+// 管理员取消一局请求参数,用于前后端交互参数格式约定
+type RoomApiAbortReq struct {
+	Code   string `v:"required#房号不能为空"`
+	Reason string `v:"max-length:100#原因最长为100个字符"`
+	Token  string `v:"required#管理员口令不能为空"`
+}
+
+// 按房号查询房间请求参数,用于前后端交互参数格式约定
+type RoomApiCodeReq struct {
+	Code string `v:"required#房号不能为空"`
+}
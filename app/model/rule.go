@@ -0,0 +1,43 @@
+package model
+
+import (
+	"niuniu/library/niu"
+)
+
+// 牌局规则,对应配置文件中的rules.sets
+type RuleSet struct {
+	Name         string         `json:"name"`         // 规则名称,唯一标识
+	Title        string         `json:"title"`        // 显示给玩家的名称
+	Mode         string         `json:"mode"`         // 结算模式,banker为庄家模式(默认),winner为通吃模式
+	Banker       string         `json:"banker"`       // 上庄方式,fixed/rotate/random/bull/grab,默认fixed
+	GrabMax      int            `json:"grabMax"`      // 抢庄允许的最高倍数,默认4
+	GrabWindow   int            `json:"grabWindow"`   // 抢庄时间,单位秒,默认10
+	Reveal       int            `json:"reveal"`       // 选庄前先发几张明牌,0为选完庄再一次发五张,明牌抢庄为4
+	Bets         []int          `json:"bets"`         // 闲家允许的下注分数,从小到大排列,默认1/2/3/5
+	BetWindow    int            `json:"betWindow"`    // 下注时间,单位秒,默认10
+	RevealWindow int            `json:"revealWindow"` // 亮牌时间,单位秒,超时自动亮牌,默认15
+	PushCap      int            `json:"pushCap"`      // 推注上限,上一局赢了的闲家可以把本金加赢的分数一起下注,0为不允许推注
+	AfkMax       int            `json:"afkMax"`       // 连续几局超时没有操作后自动站起,默认3
+	Specials     []string       `json:"specials"`     // 启用的特殊牌型英文名,按从大到小排列
+	Multiples    map[string]int `json:"multiples"`    // 每种牌型的倍数,key为牌型英文名,没有配置的按1倍
+	Evaluator    *niu.Evaluator `json:"-"`            // 按Specials生成的牌型计算器
+}
+
+// 获取牌型的倍数
+func (r *RuleSet) Multiple(c niu.Category) int {
+	if n, ok := r.Multiples[c.Key()]; ok && n > 0 {
+		return n
+	}
+	return 1
+}
+
+// 最大的牌型倍数,用于计算一局最多输多少
+func (r *RuleSet) MaxMultiple() int {
+	max := 1
+	for _, n := range r.Multiples {
+		if n > max {
+			max = n
+		}
+	}
+	return max
+}
@@ -0,0 +1,31 @@
+package model
+
+import (
+	"niuniu/library/niu"
+)
+
+// 一个玩家在整场牌局中的战绩
+type SessionPlayer struct {
+	Name     string      `json:"name"`     // 玩家昵称
+	Score    int         `json:"score"`    // 总分
+	Rounds   int         `json:"rounds"`   // 打了几局
+	Wins     int         `json:"wins"`     // 赢了几局
+	Banker   int         `json:"banker"`   // 坐庄次数
+	BullBull int         `json:"bullBull"` // 牛牛次数
+	Best     *niu.Result `json:"best"`     // 最大的一手牌
+}
+
+// 取消的一局
+type RoundAbort struct {
+	Round  int    `json:"round"`  // 局数编号
+	Reason string `json:"reason"` // 取消的原因
+	Time   int64  `json:"time"`   // 取消的时间,毫秒
+}
+
+// 整场牌局的战绩汇总
+type SessionSummary struct {
+	Rounds  int             `json:"rounds"`  // 设置的局数,0为不限局数
+	Played  int             `json:"played"`  // 已经打完的局数,不包括取消的局
+	Players []SessionPlayer `json:"players"` // 按总分从高到低排列
+	Aborted []RoundAbort    `json:"aborted"` // 取消的局
+}
@@ -0,0 +1,30 @@
+package model
+
+import (
+	"niuniu/library/niu"
+)
+
+// 结算模式
+const (
+	SettleModeBanker = "banker" // 庄家与每个闲家单独比牌
+	SettleModeWinner = "winner" // 最大的牌通吃,其他人按赢家的倍数输
+)
+
+// 参与结算的一手牌
+type SettleHand struct {
+	Name   string     // 玩家昵称
+	Hand   niu.Result // 牌型计算结果
+	Banker bool       // 是否为庄家
+	Bet    int        // 下注的分数,小于1按1算
+	Grab   int        // 庄家抢庄的倍数,只对庄家有效,小于1按1算
+}
+
+// 单个玩家的结算结果
+type SettleItem struct {
+	Name     string     `json:"name"`     // 玩家昵称
+	Hand     niu.Result `json:"hand"`     // 牌型计算结果
+	Banker   bool       `json:"banker"`   // 是否为庄家
+	Multiple int        `json:"multiple"` // 自己牌型的倍数
+	Bet      int        `json:"bet"`      // 下注的分数,庄家为0
+	Delta    int        `json:"delta"`    // 本局输赢,正数为赢,负数为输
+}
@@ -0,0 +1,36 @@
+package model
+
+// 牌桌阶段
+const (
+	PhaseWaiting = "waiting" // 等待玩家加入
+	PhaseReady   = "ready"   // 人数够了,倒计时开局
+	PhaseBanker  = "banker"  // 选庄
+	PhaseBetting = "betting" // 闲家下注
+	PhaseDealing = "dealing" // 发牌
+	PhaseReveal  = "reveal"  // 亮牌
+	PhaseSettled = "settled" // 已结算
+	PhaseClosed  = "closed"  // 房间已解散,任何阶段都可以解散
+)
+
+// 阶段的中文名,用于提示玩家
+var PhaseNames = map[string]string{
+	PhaseWaiting: "等待加入",
+	PhaseReady:   "准备开局",
+	PhaseBanker:  "选庄",
+	PhaseBetting: "下注",
+	PhaseDealing: "发牌",
+	PhaseReveal:  "亮牌",
+	PhaseSettled: "结算",
+	PhaseClosed:  "已解散",
+}
+
+// 合法的阶段切换,key为当前阶段,value为可以切换到的阶段,一局进行中取消本局时回到等待加入
+var PhaseTransitions = map[string][]string{
+	PhaseWaiting: {PhaseReady, PhaseClosed},
+	PhaseReady:   {PhaseWaiting, PhaseBanker, PhaseClosed},
+	PhaseBanker:  {PhaseBetting, PhaseWaiting, PhaseClosed},
+	PhaseBetting: {PhaseDealing, PhaseWaiting, PhaseClosed},
+	PhaseDealing: {PhaseReveal, PhaseWaiting, PhaseClosed},
+	PhaseReveal:  {PhaseSettled, PhaseWaiting, PhaseClosed},
+	PhaseSettled: {PhaseWaiting, PhaseClosed},
+}
@@ -0,0 +1,14 @@
+package model
+
+// 筹码余额
+type WalletInfo struct {
+	Account string `json:"account"` // 钱包账号,跟着会话走
+	Balance int    `json:"balance"` // 余额
+}
+
+// 增加或者扣除筹码请求参数,用于前后端交互参数格式约定
+type WalletApiChangeReq struct {
+	Account string `v:"required#钱包账号不能为空"`
+	Amount  int    `v:"min:1#筹码数量必须大于0"`
+	Token   string `v:"required#管理员口令不能为空"`
+}
@@ -0,0 +1,55 @@
+package service
+
+import (
+	"fmt"
+
+	"niuniu/app/model"
+
+	"github.com/gogf/gf/errors/gerror"
+	"github.com/gogf/gf/frame/g"
+)
+
+// 管理员取消正在进行的一局,reason为取消的原因
+func (t *Table) Abort(reason string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if reason == "" {
+		reason = "管理员取消了本局"
+	}
+	return t.abort(reason)
+}
+
+// 一局进行中出了错(牌不够了、找不到玩家的牌等),记录错误并取消本局
+func (t *Table) fail(err error) {
+	g.Log().Error(err)
+	if err := t.abort(err.Error()); err != nil {
+		g.Log().Error(err)
+	}
+}
+
+// 取消本局,下注全部退回,本局不计分也不算局数,记录取消的原因并通知房间里的玩家,然后回到等待加入
+func (t *Table) abort(reason string) error {
+	if !t.inRound() || t.scored {
+		return gerror.New("现在没有进行中的牌局")
+	}
+	if t.choice != nil {
+		t.choice.Cancel()
+		t.choice = nil
+	}
+	refunds := []model.BetItem{}
+	for _, p := range t.players {
+		if bet, ok := t.bets[p.name]; ok {
+			refunds = append(refunds, model.BetItem{Name: p.name, Bet: bet, Push: t.pushed[p.name]})
+		}
+	}
+	t.board.Abort(t.round, reason)
+	t.publish(model.MsgAbort, model.AbortPayload{
+		Room:    t.id,
+		Round:   t.round,
+		Reason:  reason,
+		Refunds: refunds,
+	})
+	t.broadcast(fmt.Sprintf("本局已取消,原因:%s,下注全部退回,本局不计分", reason))
+	t.reset()
+	return nil
+}
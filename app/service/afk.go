@@ -0,0 +1,48 @@
+package service
+
+import (
+	"fmt"
+
+	"niuniu/app/model"
+)
+
+// 抢庄或者下注结束,超时没有选择的玩家按默认选择处理,并记下本局超时了
+func (t *Table) timeout(c *Choice, values map[string]int) {
+	for _, name := range c.Missed() {
+		p := t.player(name)
+		if p == nil {
+			continue
+		}
+		p.idled = true
+		if t.phase == model.PhaseBanker {
+			t.broadcast(fmt.Sprintf("%s超时没有抢庄,默认不抢", name))
+		} else {
+			t.broadcast(fmt.Sprintf("%s超时没有下注,默认下注%d分", name, values[name]))
+		}
+	}
+}
+
+// 一局结束时累计超时的局数,一局里有超时就加一,都按时操作了就清零
+func (t *Table) count(p *tablePlayer) {
+	if p.idled {
+		p.afk++
+	} else {
+		p.afk = 0
+	}
+	p.idled = false
+}
+
+// 连续超时规则中AfkMax局的玩家在本局结束后自动站起,观战人数满了时离开牌桌
+func (t *Table) idle(p *tablePlayer) {
+	p.afk = 0
+	n := t.rule.AfkMax
+	if len(t.watchers()) >= t.opt.Watchers {
+		t.send(p, fmt.Sprintf("您连续%d局超时,已经离开了牌桌", n))
+		t.remove(p)
+		t.broadcast(fmt.Sprintf("%s连续%d局超时,离开了牌桌,当前人数%d", p.name, n, len(t.members)))
+		return
+	}
+	seat := p.seat
+	t.stand(p)
+	t.broadcast(fmt.Sprintf("%s连续%d局超时,自动站起,让出了%d号座位,输入坐下可以重新坐下", p.name, n, seat))
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"time"
+
+	"niuniu/app/model"
+	"niuniu/library/niu"
+
+	"github.com/gogf/gf/errors/gerror"
+	"github.com/gogf/gf/util/grand"
+)
+
+// 选庄服务
+var Banker = bankerService{}
+
+type bankerService struct{}
+
+// 按上庄方式选出本局的庄家
+func (s *bankerService) Select(req model.BankerSelect) (model.BankerResult, error) {
+	if len(req.Players) == 0 {
+		return model.BankerResult{}, gerror.New("没有玩家,无法选庄")
+	}
+	res := model.BankerResult{Multiple: 1}
+	switch req.Mode {
+	case model.BankerFixed, "":
+		res.Name = s.owner(req)
+	case model.BankerRotate:
+		res.Name = s.owner(req)
+		for i, name := range req.Players {
+			if name == req.Last {
+				res.Name = req.Players[(i+1)%len(req.Players)]
+				break
+			}
+		}
+	case model.BankerRandom:
+		res.Name = req.Players[grand.Intn(len(req.Players))]
+	case model.BankerBull:
+		res.Name = s.bull(req)
+	case model.BankerGrab:
+		res.Name, res.Multiple = s.grab(req)
+	default:
+		return res, gerror.Newf("未知的上庄方式: %s", req.Mode)
+	}
+	return res, nil
+}
+
+// 房主在座时房主坐庄,否则第一个入座的玩家坐庄
+func (s *bankerService) owner(req model.BankerSelect) string {
+	if s.seated(req.Players, req.Owner) {
+		return req.Owner
+	}
+	return req.Players[0]
+}
+
+// 牛牛上庄:上一局牌型在牛牛及以上的玩家中牌最大的坐庄,没有人拿到牛牛则上一局的庄家连庄
+func (s *bankerService) bull(req model.BankerSelect) string {
+	var best *model.SettleItem
+	for i, v := range req.History {
+		if v.Hand.Category != niu.BullBull && !v.Hand.Category.IsSpecial() {
+			continue
+		}
+		if !s.seated(req.Players, v.Name) {
+			continue
+		}
+		if best == nil || v.Hand.Beats(best.Hand) {
+			best = &req.History[i]
+		}
+	}
+	if best != nil {
+		return best.Name
+	}
+	if s.seated(req.Players, req.Last) {
+		return req.Last
+	}
+	return s.owner(req)
+}
+
+// 抢庄:出价最高的坐庄,一样高的随机一个;都不抢时随机一个按1倍坐庄
+func (s *bankerService) grab(req model.BankerSelect) (string, int) {
+	top := 0
+	var names []string
+	for _, name := range req.Players {
+		bid := req.Bids[name]
+		switch {
+		case bid > top:
+			top = bid
+			names = []string{name}
+		case bid == top:
+			names = append(names, name)
+		}
+	}
+	if top < 1 {
+		top = 1
+	}
+	return names[grand.Intn(len(names))], top
+}
+
+func (s *bankerService) seated(players []string, name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, v := range players {
+		if v == name {
+			return true
+		}
+	}
+	return false
+}
+
+// 开始抢庄,max为允许的最高倍数,window为抢庄时间,结束时调用done,没有出价的玩家按不抢处理
+func (s *bankerService) StartGrab(players []string, max int, window time.Duration, done func(bids map[string]int)) *Choice {
+	return newChoice("抢庄", players, window, func(name string, v int) error {
+		if v < 0 || v > max {
+			return gerror.Newf("抢庄倍数只能是0到%d", max)
+		}
+		return nil
+	}, 0, done)
+}
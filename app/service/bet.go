@@ -0,0 +1,81 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"niuniu/app/model"
+
+	"github.com/gogf/gf/errors/gerror"
+	"github.com/gogf/gf/util/gconv"
+)
+
+// 下注服务
+var Bet = betService{}
+
+type betService struct{}
+
+// 开始下注,只有闲家需要下注,levels为允许的下注分数,push为可以推注的玩家与推注分数,
+// 时间到了还没有下注的玩家按最小的分数下注,结束时调用done
+func (s *betService) Start(rs *model.RuleSet, players []string, push map[string]int, done func(bets map[string]int)) *Choice {
+	return newChoice("下注", players, time.Duration(rs.BetWindow)*time.Second, func(name string, v int) error {
+		if push[name] > 0 && v == push[name] {
+			return nil
+		}
+		return s.Check(rs, v)
+	}, rs.Bets[0], done)
+}
+
+// 校验下注分数是否在允许的范围内
+func (s *betService) Check(rs *model.RuleSet, v int) error {
+	if s.isLevel(rs, v) {
+		return nil
+	}
+	return gerror.Newf("下注分数只能是%s", s.Levels(rs))
+}
+
+// 计算推注分数,上一局作为闲家赢了,并且上一局没有推注的玩家可以推注,
+// 推注分数为上一局的下注加上赢的分数,不超过推注上限。不能推注时返回0
+func (s *betService) Push(rs *model.RuleSet, last model.SettleItem, pushed bool) int {
+	if rs.PushCap <= 0 || last.Banker || last.Delta <= 0 || pushed {
+		return 0
+	}
+	v := last.Bet + last.Delta
+	if v > rs.PushCap {
+		v = rs.PushCap
+	}
+	// 推注分数不比普通下注大就没有意义了
+	if v <= rs.Bets[len(rs.Bets)-1] {
+		return 0
+	}
+	return v
+}
+
+// 本次下注是否为推注
+func (s *betService) IsPush(rs *model.RuleSet, v int) bool {
+	return !s.isLevel(rs, v)
+}
+
+// 允许的下注分数,用于展示给玩家
+func (s *betService) Levels(rs *model.RuleSet) string {
+	return strings.Join(gconv.Strings(rs.Bets), "/")
+}
+
+// 下注提示,push为该玩家可以推注的分数,0为不能推注
+func (s *betService) Prompt(rs *model.RuleSet, push int) string {
+	prompt := fmt.Sprintf("请在%d秒内下注,可选分数:%s,超时按%d分下注", rs.BetWindow, s.Levels(rs), rs.Bets[0])
+	if push > 0 {
+		prompt += fmt.Sprintf(",您上一局赢了,本局可以推注%d分", push)
+	}
+	return prompt
+}
+
+func (s *betService) isLevel(rs *model.RuleSet, v int) bool {
+	for _, level := range rs.Bets {
+		if v == level {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,119 @@
+package service
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gogf/gf/errors/gerror"
+	"github.com/gogf/gf/os/gtimer"
+)
+
+// 在时间窗口内收集玩家的选择(抢庄倍数、下注分数等),所有玩家都选完或者时间到了就结束
+type Choice struct {
+	mu       sync.Mutex
+	label    string
+	players  []string
+	check    func(name string, v int) error
+	fallback int
+	values   map[string]int
+	entry    *gtimer.Entry
+	done     func(values map[string]int)
+	finished bool
+}
+
+// 开始收集选择,label为显示给玩家的操作名称,check校验选择是否合法,
+// 时间到了还没有选择的玩家按fallback处理,结束时调用done
+func newChoice(label string, players []string, window time.Duration, check func(name string, v int) error, fallback int, done func(values map[string]int)) *Choice {
+	c := &Choice{
+		label:    label,
+		players:  players,
+		check:    check,
+		fallback: fallback,
+		values:   make(map[string]int),
+		done:     done,
+	}
+	// 加锁防止时间太短,定时器在entry赋值之前就触发
+	c.mu.Lock()
+	c.entry = gtimer.AddOnce(window, c.finish)
+	c.mu.Unlock()
+	return c
+}
+
+// 玩家做出选择,每个玩家只能选一次
+func (c *Choice) Choose(name string, v int) error {
+	c.mu.Lock()
+	if c.finished {
+		c.mu.Unlock()
+		return gerror.Newf("%s已经结束了", c.label)
+	}
+	if !c.contains(name) {
+		c.mu.Unlock()
+		return gerror.Newf("您不需要%s", c.label)
+	}
+	if _, ok := c.values[name]; ok {
+		c.mu.Unlock()
+		return gerror.Newf("您已经%s过了", c.label)
+	}
+	if err := c.check(name, v); err != nil {
+		c.mu.Unlock()
+		return err
+	}
+	c.values[name] = v
+	all := len(c.values) == len(c.players)
+	c.mu.Unlock()
+	if all {
+		c.finish()
+	}
+	return nil
+}
+
+// 结束收集,只会执行一次
+func (c *Choice) finish() {
+	c.mu.Lock()
+	if c.finished {
+		c.mu.Unlock()
+		return
+	}
+	c.finished = true
+	c.entry.Close()
+	values := make(map[string]int, len(c.players))
+	for _, name := range c.players {
+		if v, ok := c.values[name]; ok {
+			values[name] = v
+		} else {
+			values[name] = c.fallback
+		}
+	}
+	c.mu.Unlock()
+	c.done(values)
+}
+
+// 没有在时间窗口内做出选择的玩家,按fallback处理了
+func (c *Choice) Missed() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	names := []string{}
+	for _, name := range c.players {
+		if _, ok := c.values[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// 取消收集,不会调用done
+func (c *Choice) Cancel() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.finished = true
+	c.entry.Close()
+}
+
+func (c *Choice) contains(name string) bool {
+	for _, v := range c.players {
+		if v == name {
+			return true
+		}
+	}
+	return false
+}
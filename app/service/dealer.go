@@ -0,0 +1,103 @@
+package service
+
+import (
+	"sync"
+
+	"niuniu/library/card"
+	"niuniu/library/niu"
+
+	"github.com/gogf/gf/errors/gerror"
+)
+
+// 发牌服务
+var Dealer = dealerService{}
+
+type dealerService struct{}
+
+// 一局的发牌过程,可以先发一部分牌(明牌抢庄先发四张),等抢庄下注结束后再补齐
+type Deal struct {
+	mu      sync.RWMutex
+	deck    *card.Deck
+	players []string
+	hands   map[string][]card.Card
+}
+
+// 开始一局发牌,使用一副去掉大小王的牌并洗好
+func (s *dealerService) Start(players []string) *Deal {
+	return &Deal{
+		deck:    card.NewDeck(1, false).Shuffle(),
+		players: players,
+		hands:   make(map[string][]card.Card, len(players)),
+	}
+}
+
+// 给每个玩家发到n张牌,返回每个玩家这次新发的牌
+func (d *Deal) Reveal(n int) (map[string][]card.Card, error) {
+	if n > niu.HandSize {
+		n = niu.HandSize
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	need := 0
+	for _, name := range d.players {
+		if len(d.hands[name]) < n {
+			need += n - len(d.hands[name])
+		}
+	}
+	if need > d.deck.Len() {
+		return nil, card.ErrNotEnough
+	}
+	dealt := make(map[string][]card.Card, len(d.players))
+	for _, name := range d.players {
+		if len(d.hands[name]) >= n {
+			continue
+		}
+		cards, err := d.deck.Deal(n - len(d.hands[name]))
+		if err != nil {
+			return nil, err
+		}
+		d.hands[name] = append(d.hands[name], cards...)
+		dealt[name] = cards
+	}
+	return dealt, nil
+}
+
+// 把每个玩家的牌补齐到五张,返回每个玩家这次新发的牌
+func (d *Deal) Complete() (map[string][]card.Card, error) {
+	return d.Reveal(niu.HandSize)
+}
+
+// 玩家当前手上的牌,返回的是副本,还没有发牌时(选完庄再发牌的玩法)为空
+func (d *Deal) Hand(name string) ([]card.Card, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	hand, ok := d.hands[name]
+	if !ok && !d.has(name) {
+		return nil, gerror.Newf("%s不在本局玩家中", name)
+	}
+	cards := make([]card.Card, len(hand))
+	copy(cards, hand)
+	return cards, nil
+}
+
+// 是否为本局玩家
+func (d *Deal) has(name string) bool {
+	for _, v := range d.players {
+		if v == name {
+			return true
+		}
+	}
+	return false
+}
+
+// 是否每个玩家都已经发满五张
+func (d *Deal) Done() bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	for _, name := range d.players {
+		if len(d.hands[name]) < niu.HandSize {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,28 @@
+package service
+
+import (
+	"testing"
+)
+
+func TestDealHand(t *testing.T) {
+	d := Dealer.Start([]string{"甲", "乙"})
+	// 还没有发牌时本局玩家的牌为空,不是错误
+	if cards, err := d.Hand("甲"); err != nil || len(cards) != 0 {
+		t.Fatalf("发牌之前: %v %v", cards, err)
+	}
+	if _, err := d.Hand("丙"); err == nil {
+		t.Fatal("不在本局玩家中应该返回错误")
+	}
+	if _, err := d.Reveal(4); err != nil {
+		t.Fatal(err)
+	}
+	if cards, err := d.Hand("乙"); err != nil || len(cards) != 4 {
+		t.Fatalf("明牌之后: %v %v", cards, err)
+	}
+	if _, err := d.Complete(); err != nil {
+		t.Fatal(err)
+	}
+	if cards, err := d.Hand("甲"); err != nil || len(cards) != 5 || !d.Done() {
+		t.Fatalf("补齐之后: %v %v", cards, err)
+	}
+}
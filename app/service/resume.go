@@ -0,0 +1,129 @@
+package service
+
+import (
+	"fmt"
+
+	"niuniu/app/model"
+	"niuniu/library/niu"
+
+	"github.com/gogf/gf/errors/gerror"
+	"github.com/gogf/gf/frame/g"
+)
+
+// 玩家重新连上,换成新的连接。断线的玩家先补发断线期间错过的消息,再发牌桌的快照;
+// 旧的连接还没断开时(例如刷新了页面)关闭旧的连接,新的连接只收到快照
+func (t *Table) Resume(name string, conn Conn) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.phase == model.PhaseClosed {
+		return gerror.New("房间已经解散了")
+	}
+	p := t.member(name)
+	if p == nil {
+		return gerror.New("您不在这个牌桌上")
+	}
+	old := p.conn
+	p.conn = conn
+	if p.quit {
+		p.quit = false
+		t.replay(p, p.acked)
+	} else if old != nil && old != conn {
+		old.Close()
+	}
+	t.notify(p, model.MsgSnapshot, t.snapshot(p))
+	if p.seat > 0 {
+		t.broadcast(fmt.Sprintf("%s重新连上了,坐在%d号座位", name, p.seat))
+	}
+	t.checkReady()
+	return nil
+}
+
+// 断线超过reconnectGrace还没有重新连上,不在本局玩家中时让出座位,本局玩家等本局结束后再让出座位
+func (t *Table) expire(p *tablePlayer) {
+	if t.playing(p) {
+		return
+	}
+	seat := p.seat
+	t.remove(p)
+	t.broadcast(fmt.Sprintf("%s断线超时,让出了%d号座位,当前人数%d", p.name, seat, len(t.members)))
+	t.checkReady()
+}
+
+// 补发序号大于from的消息,history里已经没有from之后的消息时发牌桌的快照
+func (t *Table) Replay(name string, from int64) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	p := t.member(name)
+	if p == nil {
+		return gerror.New("您不在这个牌桌上")
+	}
+	if !t.replay(p, from) {
+		t.notify(p, model.MsgSnapshot, t.snapshot(p))
+	}
+	return nil
+}
+
+// 补发序号大于from的消息,返回history里是否还有from之后的所有消息
+func (t *Table) replay(p *tablePlayer, from int64) bool {
+	oldest := p.seq - int64(len(p.history)) + 1
+	if from < oldest-1 || from > p.seq {
+		return false
+	}
+	for _, msg := range p.history[from-oldest+1:] {
+		t.deliver(p, msg)
+	}
+	return true
+}
+
+// 牌桌当前的快照,只有本人的牌,别人的牌只有亮了的才有
+func (t *Table) snapshot(p *tablePlayer) model.SnapshotPayload {
+	s := model.SnapshotPayload{
+		Room:     t.info(),
+		Owner:    t.owner,
+		Banker:   t.banker,
+		Multiple: t.grab,
+	}
+	if !t.inRound() {
+		return s
+	}
+	for _, v := range t.players {
+		if bet, ok := t.bets[v.name]; ok {
+			s.Bets = append(s.Bets, model.BetItem{Name: v.name, Bet: bet, Push: t.pushed[v.name]})
+		}
+	}
+	if t.playing(p) {
+		cards, err := t.deal.Hand(p.name)
+		if err != nil {
+			g.Log().Error(err)
+			return s
+		}
+		s.Cards = cards
+		if len(cards) == niu.HandSize {
+			if hand, err := t.rule.Evaluator.Evaluate(cards); err == nil {
+				s.Hand = &hand
+				s.Title = hand.Category.String()
+			}
+		}
+	}
+	for _, v := range t.players {
+		if !v.revealed {
+			continue
+		}
+		cards, err := t.deal.Hand(v.name)
+		if err != nil {
+			continue
+		}
+		hand, err := t.rule.Evaluator.Evaluate(cards)
+		if err != nil {
+			continue
+		}
+		s.Reveals = append(s.Reveals, model.RevealPayload{
+			Room:  t.id,
+			Round: t.round,
+			Name:  v.name,
+			Hand:  hand,
+			Title: hand.Category.String(),
+		})
+	}
+	return s
+}
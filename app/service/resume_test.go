@@ -0,0 +1,70 @@
+package service
+
+import (
+	"testing"
+
+	"niuniu/app/model"
+)
+
+// 记录收到的消息序号的连接
+type seqConn struct {
+	seqs []int64
+}
+
+func (c *seqConn) Send(msg model.ChatMsg) error {
+	c.seqs = append(c.seqs, msg.Seq)
+	return nil
+}
+
+func (c *seqConn) Close() {}
+
+func (c *seqConn) Account() string {
+	return ""
+}
+
+func TestReplay(t *testing.T) {
+	// history只保留最近historyMax条,最早的一条序号为11
+	total := int64(historyMax + 10)
+	oldest := total - historyMax + 1
+	cases := []struct {
+		name  string
+		from  int64
+		ok    bool
+		first int64 // 补发的第一条的序号,没有补发时为0
+		count int
+	}{
+		{"已经收到了最后一条", total, true, 0, 0},
+		{"从最早的一条开始补发", oldest - 1, true, oldest, historyMax},
+		{"补发一部分", 50, true, 51, int(total - 50)},
+		{"比最早的一条还早", oldest - 2, false, 0, 0},
+		{"序号为负数", -1, false, 0, 0},
+		{"比最后一条还大", total + 1, false, 0, 0},
+	}
+	for _, c := range cases {
+		conn := &seqConn{}
+		p := &tablePlayer{name: "甲", seat: 1, conn: conn}
+		tb := &Table{}
+		for i := int64(0); i < total; i++ {
+			tb.write(p, model.ChatMsg{Type: model.MsgSend})
+		}
+		conn.seqs = nil
+		if ok := tb.replay(p, c.from); ok != c.ok {
+			t.Errorf("%s: 返回%v,应该是%v", c.name, ok, c.ok)
+		}
+		if len(conn.seqs) != c.count {
+			t.Errorf("%s: 补发了%d条,应该是%d条", c.name, len(conn.seqs), c.count)
+			continue
+		}
+		for i, seq := range conn.seqs {
+			if seq != c.first+int64(i) {
+				t.Errorf("%s: 第%d条的序号为%d,应该是%d", c.name, i+1, seq, c.first+int64(i))
+				break
+			}
+		}
+	}
+	// 还没有收到过消息时从0开始补发,什么也不用补
+	p := &tablePlayer{name: "乙", seat: 1, conn: &seqConn{}}
+	if !(&Table{}).replay(p, 0) {
+		t.Error("没有消息时从0补发应该成功")
+	}
+}
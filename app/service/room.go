@@ -0,0 +1,350 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"niuniu/app/model"
+
+	"github.com/gogf/gf/errors/gerror"
+	"github.com/gogf/gf/util/grand"
+)
+
+// 创建之后多久还没有人加入的房间会被清理
+const roomIdle = 10 * time.Minute
+
+// 房间管理服务,每个房间一张牌桌,各自独立开局
+var Room = roomService{
+	tables: make(map[int]*Table),
+	codes:  make(map[string]int),
+	users:  make(map[string]int),
+}
+
+type roomService struct {
+	mu     sync.Mutex
+	seq    int            // 最后分配的房间编号
+	tables map[int]*Table // 房间编号对应的牌桌
+	codes  map[string]int // 房号对应的房间编号
+	users  map[string]int // 玩家所在的房间编号
+}
+
+// 按规则创建房间,rule为空时使用默认规则
+func (s *roomService) Create(rule string, opt model.RoomOption) (*Table, error) {
+	rs, err := s.rule(rule, opt)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prune()
+	return s.create(rs, opt), nil
+}
+
+// 创建房间并加入
+func (s *roomService) Open(rule string, opt model.RoomOption, name string, conn Conn) (*Table, error) {
+	rs, err := s.rule(rule, opt)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.check(name); err != nil {
+		return nil, err
+	}
+	s.prune()
+	t := s.create(rs, opt)
+	if err := s.join(t, opt.Passcode, name, conn); err != nil {
+		delete(s.tables, t.ID())
+		delete(s.codes, t.Code())
+		return nil, err
+	}
+	return t, nil
+}
+
+// 获取房间
+func (s *roomService) Get(id int) (*Table, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.tables[id]
+	if !ok {
+		return nil, gerror.Newf("房间%d不存在", id)
+	}
+	return t, nil
+}
+
+// 按房号获取房间
+func (s *roomService) Code(code string) (*Table, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id, ok := s.codes[code]
+	if !ok {
+		return nil, gerror.Newf("房号%s不存在", code)
+	}
+	return s.tables[id], nil
+}
+
+// 公开的房间列表,按房间编号排序,私人房间不显示
+func (s *roomService) List() []model.RoomInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prune()
+	list := make([]model.RoomInfo, 0, len(s.tables))
+	for _, t := range s.tables {
+		if info := t.Info(); !info.Private {
+			list = append(list, info)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Id < list[j].Id
+	})
+	return list
+}
+
+// 房间列表的文字说明
+func (s *roomService) Describe() string {
+	list := s.List()
+	if len(list) == 0 {
+		return "当前没有房间,输入建房创建一个房间"
+	}
+	items := make([]string, len(list))
+	for i, v := range list {
+		items[i] = fmt.Sprintf("房间%d(%s,%s,%d/%d人)", v.Id, v.Title, model.PhaseNames[v.Phase], len(v.Players), v.Max)
+	}
+	return strings.Join(items, ",")
+}
+
+// 玩家所在的房间,不在房间里时返回nil
+func (s *roomService) Of(name string) *Table {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prune()
+	if id, ok := s.users[name]; ok {
+		return s.tables[id]
+	}
+	return nil
+}
+
+// 加入房间列表中的房间,一个玩家同时只能在一个房间里,私人房间只能通过房号加入
+func (s *roomService) Join(id int, name string, conn Conn) (*Table, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.tables[id]
+	if !ok || t.Info().Private {
+		return nil, gerror.Newf("房间%d不存在", id)
+	}
+	return t, s.join(t, "", name, conn)
+}
+
+// 观战房间列表中的房间
+func (s *roomService) Watch(id int, name string, conn Conn) (*Table, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.tables[id]
+	if !ok || t.Info().Private {
+		return nil, gerror.Newf("房间%d不存在", id)
+	}
+	return t, s.watch(t, "", name, conn)
+}
+
+// 通过房号观战
+func (s *roomService) WatchCode(code, passcode, name string, conn Conn) (*Table, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id, ok := s.codes[code]
+	if !ok {
+		return nil, gerror.Newf("房号%s不存在", code)
+	}
+	t := s.tables[id]
+	return t, s.watch(t, passcode, name, conn)
+}
+
+// 通过房号加入房间
+func (s *roomService) JoinCode(code, passcode, name string, conn Conn) (*Table, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id, ok := s.codes[code]
+	if !ok {
+		return nil, gerror.Newf("房号%s不存在", code)
+	}
+	t := s.tables[id]
+	return t, s.join(t, passcode, name, conn)
+}
+
+// 快速加入,加入第一个还能加入的房间,没有的话按默认规则创建一个
+func (s *roomService) Quick(name string, conn Conn) (*Table, error) {
+	rs, err := Rule.Default()
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.check(name); err != nil {
+		return nil, err
+	}
+	s.prune()
+	var t *Table
+	ids := make([]int, 0, len(s.tables))
+	for id := range s.tables {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	for _, id := range ids {
+		if s.tables[id].Joinable() {
+			t = s.tables[id]
+			break
+		}
+	}
+	if t == nil {
+		t = s.create(rs, model.RoomOption{})
+	}
+	return t, s.join(t, "", name, conn)
+}
+
+// 离开房间,一局开始之后不能离开
+func (s *roomService) Leave(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prune()
+	id, ok := s.users[name]
+	if !ok {
+		return gerror.New("您不在房间里")
+	}
+	if err := s.tables[id].Leave(name); err != nil {
+		return err
+	}
+	delete(s.users, name)
+	s.prune()
+	return nil
+}
+
+// 玩家断开连接,坐下的玩家保留座位,在断线重连的时间内还算在房间里
+func (s *roomService) Quit(name string, conn Conn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prune()
+	id, ok := s.users[name]
+	if !ok {
+		return
+	}
+	if !s.tables[id].Quit(name, conn) {
+		delete(s.users, name)
+	}
+	s.prune()
+}
+
+// 玩家重新连上,回到原来的座位,旧的连接还没断开时顶替旧的连接,不在房间里时返回nil
+func (s *roomService) Resume(name string, conn Conn) *Table {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prune()
+	id, ok := s.users[name]
+	if !ok {
+		return nil
+	}
+	t := s.tables[id]
+	if err := t.Resume(name, conn); err != nil {
+		return nil
+	}
+	return t
+}
+
+// 校验房间设置,返回房间使用的规则
+func (s *roomService) rule(name string, opt model.RoomOption) (*model.RuleSet, error) {
+	if opt.Rounds < 0 {
+		return nil, gerror.New("局数不能小于0")
+	}
+	if opt.Vote != "" && opt.Vote != model.VoteMajority && opt.Vote != model.VoteUnanimous {
+		return nil, gerror.Newf("不支持的投票方式:%s", opt.Vote)
+	}
+	if opt.Seats != 0 && (opt.Seats < tableMinSeats || opt.Seats > tableMaxSeats) {
+		return nil, gerror.Newf("座位数只能是%d到%d个", tableMinSeats, tableMaxSeats)
+	}
+	if opt.Watchers < 0 || opt.Watchers > tableMaxWatchers {
+		return nil, gerror.Newf("观战人数只能是0到%d个", tableMaxWatchers)
+	}
+	if opt.Delay < 0 || opt.Delay > tableMaxDelay {
+		return nil, gerror.Newf("观战延迟只能是0到%d秒", tableMaxDelay)
+	}
+	if name == "" {
+		return Rule.Default()
+	}
+	return Rule.Get(name)
+}
+
+func (s *roomService) check(name string) error {
+	if id, ok := s.users[name]; ok {
+		return gerror.Newf("您已经在房间%d了,请先离开", id)
+	}
+	return nil
+}
+
+func (s *roomService) join(t *Table, passcode, name string, conn Conn) error {
+	s.prune()
+	if err := s.check(name); err != nil {
+		return err
+	}
+	if err := t.Verify(passcode); err != nil {
+		return err
+	}
+	if err := t.Join(name, conn); err != nil {
+		return err
+	}
+	s.users[name] = t.ID()
+	return nil
+}
+
+func (s *roomService) watch(t *Table, passcode, name string, conn Conn) error {
+	s.prune()
+	if err := s.check(name); err != nil {
+		return err
+	}
+	if err := t.Verify(passcode); err != nil {
+		return err
+	}
+	if err := t.Watch(name, conn); err != nil {
+		return err
+	}
+	s.users[name] = t.ID()
+	return nil
+}
+
+// 创建房间,生成一个不重复的六位数字房号
+func (s *roomService) create(rs *model.RuleSet, opt model.RoomOption) *Table {
+	code := ""
+	for {
+		code = fmt.Sprintf("%06d", grand.N(0, 999999))
+		if _, ok := s.codes[code]; !ok {
+			break
+		}
+	}
+	if opt.Seats == 0 {
+		opt.Seats = tableSeats
+	}
+	if opt.Watchers == 0 {
+		opt.Watchers = tableWatchers
+	}
+	s.seq++
+	t := NewTable(s.seq, code, rs, opt)
+	s.tables[s.seq] = t
+	s.codes[code] = s.seq
+	return t
+}
+
+// 清理已经没人用的房间,已经解散的房间里的玩家也一起清理
+func (s *roomService) prune() {
+	for id, t := range s.tables {
+		if t.Abandoned(roomIdle) {
+			delete(s.tables, id)
+			delete(s.codes, t.Code())
+		}
+	}
+	// 断线超时被移出牌桌的玩家也不再算在房间里
+	for name, id := range s.users {
+		if t, ok := s.tables[id]; !ok || !t.Has(name) {
+			delete(s.users, name)
+		}
+	}
+}
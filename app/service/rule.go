@@ -0,0 +1,227 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"niuniu/app/model"
+	"niuniu/library/niu"
+
+	"github.com/gogf/gf/encoding/gjson"
+	"github.com/gogf/gf/errors/gerror"
+	"github.com/gogf/gf/frame/g"
+)
+
+// 牌局规则管理服务
+var Rule = ruleService{}
+
+type ruleService struct {
+	once sync.Once
+	sets map[string]*model.RuleSet
+	def  string
+	err  error
+}
+
+// 上庄方式的中文名
+var bankerNames = map[string]string{
+	model.BankerFixed:  "房主坐庄",
+	model.BankerRotate: "轮流坐庄",
+	model.BankerRandom: "随机坐庄",
+	model.BankerBull:   "牛牛上庄",
+	model.BankerGrab:   "抢庄",
+}
+
+// 没有配置文件时使用的规则,与原来写死的倍数一致
+var classicRuleSet = model.RuleSet{
+	Name:     "classic",
+	Title:    "经典规则",
+	Mode:     model.SettleModeBanker,
+	Banker:   model.BankerFixed,
+	Specials: []string{"fivesmall", "bomb", "fiveface", "fourface"},
+	Multiples: map[string]int{
+		"bull7":     2,
+		"bull8":     2,
+		"bull9":     2,
+		"bullbull":  3,
+		"fourface":  4,
+		"fiveface":  5,
+		"bomb":      6,
+		"fivesmall": 8,
+	},
+}
+
+// 从配置文件加载全部规则,只加载一次
+func (s *ruleService) load() error {
+	s.once.Do(func() {
+		// 倍数是map,gconv转换不了,这里走一遍json
+		var sets []*model.RuleSet
+		if raw := g.Cfg().Get("rules.sets"); raw != nil {
+			b, err := gjson.Encode(raw)
+			if err == nil {
+				err = gjson.DecodeTo(b, &sets)
+			}
+			if err != nil {
+				s.err = gerror.Wrap(err, "规则配置格式错误")
+				return
+			}
+		}
+		if len(sets) == 0 {
+			rs := classicRuleSet
+			sets = append(sets, &rs)
+		}
+		s.sets = make(map[string]*model.RuleSet, len(sets))
+		for _, rs := range sets {
+			if err := s.build(rs); err != nil {
+				s.err = err
+				return
+			}
+			if _, ok := s.sets[rs.Name]; ok {
+				s.err = gerror.Newf("规则%s重复配置", rs.Name)
+				return
+			}
+			s.sets[rs.Name] = rs
+		}
+		s.def = g.Cfg().GetString("rules.default", sets[0].Name)
+		if _, ok := s.sets[s.def]; !ok {
+			s.err = gerror.Newf("默认规则%s不存在", s.def)
+		}
+	})
+	return s.err
+}
+
+// 校验规则配置,并生成牌型计算器
+func (s *ruleService) build(rs *model.RuleSet) error {
+	if rs.Name == "" {
+		return gerror.New("规则名称不能为空")
+	}
+	if rs.Title == "" {
+		rs.Title = rs.Name
+	}
+	switch rs.Mode {
+	case "":
+		rs.Mode = model.SettleModeBanker
+	case model.SettleModeBanker, model.SettleModeWinner:
+	default:
+		return gerror.Newf("规则%s的结算模式%s不存在", rs.Name, rs.Mode)
+	}
+	switch rs.Banker {
+	case "":
+		rs.Banker = model.BankerFixed
+	case model.BankerFixed, model.BankerRotate, model.BankerRandom, model.BankerBull, model.BankerGrab:
+	default:
+		return gerror.Newf("规则%s的上庄方式%s不存在", rs.Name, rs.Banker)
+	}
+	if rs.GrabMax < 1 {
+		rs.GrabMax = 4
+	}
+	if rs.GrabWindow < 1 {
+		rs.GrabWindow = 10
+	}
+	if rs.Reveal < 0 || rs.Reveal >= niu.HandSize {
+		return gerror.Newf("规则%s的明牌张数只能是0到%d", rs.Name, niu.HandSize-1)
+	}
+	if len(rs.Bets) == 0 {
+		rs.Bets = []int{1, 2, 3, 5}
+	}
+	sort.Ints(rs.Bets)
+	if rs.Bets[0] < 1 {
+		return gerror.Newf("规则%s的下注分数必须大于0", rs.Name)
+	}
+	if rs.BetWindow < 1 {
+		rs.BetWindow = 10
+	}
+	if rs.RevealWindow < 1 {
+		rs.RevealWindow = 15
+	}
+	if rs.AfkMax < 1 {
+		rs.AfkMax = 3
+	}
+	if rs.PushCap < 0 {
+		return gerror.Newf("规则%s的推注上限不能小于0", rs.Name)
+	}
+	specials := make([]niu.Category, 0, len(rs.Specials))
+	for _, v := range rs.Specials {
+		c, err := niu.ParseCategory(v)
+		if err != nil {
+			return gerror.Wrapf(err, "规则%s配置错误", rs.Name)
+		}
+		specials = append(specials, c)
+	}
+	for k := range rs.Multiples {
+		if _, err := niu.ParseCategory(k); err != nil {
+			return gerror.Wrapf(err, "规则%s的倍数配置错误", rs.Name)
+		}
+	}
+	e, err := niu.NewEvaluator(specials...)
+	if err != nil {
+		return gerror.Wrapf(err, "规则%s配置错误", rs.Name)
+	}
+	rs.Evaluator = e
+	return nil
+}
+
+// 根据名称获取规则
+func (s *ruleService) Get(name string) (*model.RuleSet, error) {
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	rs, ok := s.sets[name]
+	if !ok {
+		return nil, gerror.Newf("规则%s不存在", name)
+	}
+	return rs, nil
+}
+
+// 获取默认规则
+func (s *ruleService) Default() (*model.RuleSet, error) {
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s.sets[s.def], nil
+}
+
+// 获取全部规则
+func (s *ruleService) List() ([]*model.RuleSet, error) {
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	list := make([]*model.RuleSet, 0, len(s.sets))
+	for _, rs := range s.sets {
+		list = append(list, rs)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+	return list, nil
+}
+
+// 规则的说明,用于规则列表
+func (s *ruleService) Infos(list []*model.RuleSet) []model.RuleInfo {
+	infos := make([]model.RuleInfo, len(list))
+	for i, rs := range list {
+		infos[i] = model.RuleInfo{
+			Name:  rs.Name,
+			Title: rs.Title,
+			Desc:  s.Describe(rs),
+		}
+	}
+	return infos
+}
+
+// 规则的文字说明,开局时展示给玩家
+func (s *ruleService) Describe(rs *model.RuleSet) string {
+	items := make([]string, 0)
+	for _, c := range rs.Evaluator.Categories() {
+		items = append(items, fmt.Sprintf("%s x%d", c, rs.Multiple(c)))
+	}
+	mode := bankerNames[rs.Banker]
+	if rs.Reveal > 0 {
+		mode = fmt.Sprintf("明牌%d张%s", rs.Reveal, mode)
+	}
+	if rs.Mode == model.SettleModeWinner {
+		mode = "通吃模式"
+	}
+	return fmt.Sprintf("当前规则:%s(%s),牌型从大到小:%s", rs.Title, mode, strings.Join(items, ","))
+}
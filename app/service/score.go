@@ -0,0 +1,114 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"niuniu/app/model"
+	"niuniu/library/niu"
+
+	"github.com/gogf/gf/os/gtime"
+)
+
+// 记分板,累计一个房间每一局的结算结果
+type Scoreboard struct {
+	rounds  int
+	played  int
+	players []*model.SessionPlayer
+	aborted []model.RoundAbort
+}
+
+// 创建记分板,rounds为设置的局数,0为不限局数
+func NewScoreboard(rounds int) *Scoreboard {
+	return &Scoreboard{rounds: rounds}
+}
+
+// 记录一局的结算结果
+func (b *Scoreboard) Record(items []model.SettleItem) {
+	b.played++
+	for _, v := range items {
+		p := b.player(v.Name)
+		p.Score += v.Delta
+		p.Rounds++
+		if v.Delta > 0 {
+			p.Wins++
+		}
+		if v.Banker {
+			p.Banker++
+		}
+		if v.Hand.Category == niu.BullBull {
+			p.BullBull++
+		}
+		if p.Best == nil || v.Hand.Beats(*p.Best) {
+			hand := v.Hand
+			p.Best = &hand
+		}
+	}
+}
+
+// 记录取消的一局,取消的局不计分也不算局数
+func (b *Scoreboard) Abort(round int, reason string) {
+	b.aborted = append(b.aborted, model.RoundAbort{
+		Round:  round,
+		Reason: reason,
+		Time:   gtime.TimestampMilli(),
+	})
+}
+
+// 已经打完的局数
+func (b *Scoreboard) Played() int {
+	return b.played
+}
+
+// 战绩汇总,按总分从高到低排列
+func (b *Scoreboard) Summary() model.SessionSummary {
+	players := make([]model.SessionPlayer, len(b.players))
+	for i, p := range b.players {
+		players[i] = *p
+	}
+	sort.SliceStable(players, func(i, j int) bool {
+		return players[i].Score > players[j].Score
+	})
+	return model.SessionSummary{
+		Rounds:  b.rounds,
+		Played:  b.played,
+		Players: players,
+		Aborted: append([]model.RoundAbort{}, b.aborted...),
+	}
+}
+
+// 战绩的文字说明
+func (b *Scoreboard) Describe() string {
+	summary := b.Summary()
+	if summary.Played == 0 {
+		return "还没有打完一局"
+	}
+	title := fmt.Sprintf("已经打了%d局", summary.Played)
+	if summary.Rounds > 0 {
+		title = fmt.Sprintf("共%d局,已经打了%d局", summary.Rounds, summary.Played)
+	}
+	items := []string{title}
+	for _, p := range summary.Players {
+		best := ""
+		if p.Best != nil {
+			best = p.Best.Category.String()
+		}
+		items = append(items, fmt.Sprintf("%s:总分%d,赢%d局,坐庄%d次,牛牛%d次,最大牌型%s", p.Name, p.Score, p.Wins, p.Banker, p.BullBull, best))
+	}
+	if len(summary.Aborted) > 0 {
+		items = append(items, fmt.Sprintf("取消了%d局", len(summary.Aborted)))
+	}
+	return strings.Join(items, "</br>")
+}
+
+func (b *Scoreboard) player(name string) *model.SessionPlayer {
+	for _, p := range b.players {
+		if p.Name == name {
+			return p
+		}
+	}
+	p := &model.SessionPlayer{Name: name}
+	b.players = append(b.players, p)
+	return p
+}
@@ -0,0 +1,231 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"niuniu/app/model"
+
+	"github.com/gogf/gf/errors/gerror"
+	"github.com/gogf/gf/frame/g"
+)
+
+// 坐下,seat为座位号,0为第一个空座位,已经坐下的玩家不在本局玩家中时可以换座位
+func (t *Table) Sit(name string, seat int) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	p := t.member(name)
+	if p == nil {
+		return gerror.New("您不在这个牌桌上")
+	}
+	if t.playing(p) {
+		return gerror.New("本局还没有结束,不能换座位")
+	}
+	if seat == 0 {
+		if seat = t.freeSeat(); seat == 0 {
+			return gerror.New("没有空座位了")
+		}
+	}
+	if seat < 0 || seat > len(t.seats) {
+		return gerror.Newf("座位号只能是1到%d", len(t.seats))
+	}
+	if p.seat == 0 {
+		if err := t.checkWallet(name, p.conn); err != nil {
+			return err
+		}
+	}
+	if v := t.seats[seat-1]; v != nil {
+		if v == p {
+			return gerror.Newf("您已经坐在%d号座位了", seat)
+		}
+		return gerror.Newf("%d号座位已经有人了", seat)
+	}
+	t.stand(p)
+	t.sit(p, seat)
+	t.broadcast(fmt.Sprintf("%s坐在了%d号座位", name, seat))
+	t.checkReady()
+	return nil
+}
+
+// 同一个钱包只能坐一个座位,否则结算时输赢会算到一起
+func (t *Table) checkWallet(name string, conn Conn) error {
+	account := conn.Account()
+	if account == "" {
+		return nil
+	}
+	for _, v := range t.seated() {
+		if v.name != name && v.conn.Account() == account {
+			return gerror.Newf("您的钱包已经有%s坐在这个牌桌上了", v.name)
+		}
+	}
+	return nil
+}
+
+// 站起,让出座位,本局玩家在本局结束之前不能站起
+func (t *Table) Stand(name string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	p := t.member(name)
+	if p == nil {
+		return gerror.New("您不在这个牌桌上")
+	}
+	if p.seat == 0 {
+		return gerror.New("您还没有坐下")
+	}
+	if t.playing(p) {
+		return gerror.New("本局还没有结束,不能站起")
+	}
+	if len(t.watchers()) >= t.opt.Watchers {
+		return gerror.New("观战人数已满,不能站起")
+	}
+	seat := p.seat
+	t.stand(p)
+	t.broadcast(fmt.Sprintf("%s离开了%d号座位", name, seat))
+	t.checkReady()
+	return nil
+}
+
+// 准备或者取消准备,坐下的玩家准备的人数够了之后倒计时开局
+func (t *Table) Ready(name string, ready bool) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	p := t.member(name)
+	if p == nil {
+		return gerror.New("您不在这个牌桌上")
+	}
+	if p.seat == 0 {
+		return gerror.New("请先坐下")
+	}
+	if t.playing(p) {
+		return gerror.New("本局还没有结束")
+	}
+	if t.phase == model.PhaseClosed || t.finished() {
+		return gerror.New("房间的牌局已经结束了")
+	}
+	if p.ready == ready {
+		if ready {
+			return gerror.New("您已经准备了")
+		}
+		return gerror.New("您还没有准备")
+	}
+	// 余额至少要够按最小分数下注输最大的牌型
+	if ready {
+		if err := Wallet.Check(p.conn.Account(), t.rule.Bets[0]*t.rule.MaxMultiple()); err != nil {
+			return err
+		}
+	}
+	p.ready = ready
+	if ready {
+		t.broadcast(fmt.Sprintf("%s已准备,准备人数%d", name, t.readyCount()))
+	} else {
+		t.broadcast(fmt.Sprintf("%s取消了准备,准备人数%d", name, t.readyCount()))
+	}
+	t.checkReady()
+	return nil
+}
+
+// 准备的人数够了就倒计时开局,倒计时中准备的人数不够了就取消开局
+func (t *Table) checkReady() {
+	n := t.readyCount()
+	switch t.phase {
+	case model.PhaseWaiting:
+		if n < tableMinPlayers {
+			return
+		}
+		if err := t.transit(model.PhaseReady); err != nil {
+			g.Log().Error(err)
+			return
+		}
+		t.broadcast(fmt.Sprintf("准备人数已够,%d秒后开局", readyDelay/time.Second))
+		t.after(readyDelay, t.startRound)
+	case model.PhaseReady:
+		if n >= tableMinPlayers {
+			return
+		}
+		if err := t.transit(model.PhaseWaiting); err != nil {
+			g.Log().Error(err)
+			return
+		}
+		t.broadcast("准备人数不够了,取消开局")
+	}
+}
+
+// 坐下并且已经准备的人数
+func (t *Table) readyCount() int {
+	n := 0
+	for _, p := range t.seated() {
+		if p.ready && !p.quit {
+			n++
+		}
+	}
+	return n
+}
+
+func (t *Table) sit(p *tablePlayer, seat int) {
+	t.seats[seat-1] = p
+	p.seat = seat
+}
+
+func (t *Table) stand(p *tablePlayer) {
+	if p.seat > 0 {
+		t.seats[p.seat-1] = nil
+	}
+	p.seat = 0
+	p.ready = false
+}
+
+// 第一个空座位的座位号,没有空座位时返回0
+func (t *Table) freeSeat() int {
+	for i, p := range t.seats {
+		if p == nil {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// 按座位号排列的坐下的玩家
+func (t *Table) seated() []*tablePlayer {
+	players := []*tablePlayer{}
+	for _, p := range t.seats {
+		if p != nil {
+			players = append(players, p)
+		}
+	}
+	return players
+}
+
+func (t *Table) seatInfo() []model.Seat {
+	seats := make([]model.Seat, len(t.seats))
+	for i, p := range t.seats {
+		seats[i].No = i + 1
+		if p != nil {
+			seats[i].Name = p.name
+			seats[i].Ready = p.ready
+			seats[i].Playing = t.playing(p)
+		}
+	}
+	return seats
+}
+
+// 一局是否正在进行
+func (t *Table) inRound() bool {
+	switch t.phase {
+	case model.PhaseBanker, model.PhaseBetting, model.PhaseDealing, model.PhaseReveal, model.PhaseSettled:
+		return true
+	}
+	return false
+}
+
+// 是否在正在进行的一局的玩家中
+func (t *Table) playing(p *tablePlayer) bool {
+	if !t.inRound() {
+		return false
+	}
+	for _, v := range t.players {
+		if v == p {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"niuniu/app/model"
+
+	"github.com/gogf/gf/errors/gerror"
+)
+
+// 结算服务
+var Settle = settleService{}
+
+type settleService struct{}
+
+// 按规则的结算模式结算一局,返回的结果与hands顺序一致
+func (s *settleService) Settle(rs *model.RuleSet, hands []model.SettleHand) ([]model.SettleItem, error) {
+	if len(hands) < 2 {
+		return nil, gerror.New("至少需要两名玩家才能结算")
+	}
+	switch rs.Mode {
+	case model.SettleModeWinner:
+		return s.Winner(rs, hands)
+	case model.SettleModeBanker, "":
+		return s.Banker(rs, hands)
+	}
+	return nil, gerror.Newf("未知的结算模式: %s", rs.Mode)
+}
+
+// 庄家结算:每个闲家单独与庄家比牌,牌一样大算庄家赢。
+// 闲家赢时庄家按闲家的牌型倍数赔付,闲家输时按庄家的牌型倍数付给庄家,抢庄的倍数另外再乘上。
+func (s *settleService) Banker(rs *model.RuleSet, hands []model.SettleHand) ([]model.SettleItem, error) {
+	items := s.items(rs, hands)
+	banker := -1
+	for i, h := range hands {
+		if h.Banker {
+			if banker >= 0 {
+				return nil, gerror.New("只能有一个庄家")
+			}
+			banker = i
+		}
+	}
+	if banker < 0 {
+		return nil, gerror.New("没有庄家,无法按庄家模式结算")
+	}
+	grab := hands[banker].Grab
+	if grab < 1 {
+		grab = 1
+	}
+	for i, h := range hands {
+		if i == banker {
+			continue
+		}
+		var delta int
+		if h.Hand.Beats(hands[banker].Hand) {
+			delta = s.bet(h) * items[i].Multiple * grab
+		} else {
+			delta = -s.bet(h) * items[banker].Multiple * grab
+		}
+		items[i].Delta += delta
+		items[banker].Delta -= delta
+	}
+	return items, nil
+}
+
+// 通吃结算:最大的一手牌赢,其他人都按赢家的牌型倍数付给赢家
+func (s *settleService) Winner(rs *model.RuleSet, hands []model.SettleHand) ([]model.SettleItem, error) {
+	items := s.items(rs, hands)
+	winner := 0
+	for i, h := range hands {
+		if h.Hand.Beats(hands[winner].Hand) {
+			winner = i
+		}
+	}
+	for i, h := range hands {
+		if i == winner {
+			continue
+		}
+		delta := s.bet(h) * items[winner].Multiple
+		items[i].Delta -= delta
+		items[winner].Delta += delta
+	}
+	return items, nil
+}
+
+// 生成没有输赢的结算结果
+func (s *settleService) items(rs *model.RuleSet, hands []model.SettleHand) []model.SettleItem {
+	items := make([]model.SettleItem, len(hands))
+	for i, h := range hands {
+		items[i] = model.SettleItem{
+			Name:     h.Name,
+			Hand:     h.Hand,
+			Banker:   h.Banker,
+			Multiple: rs.Multiple(h.Hand.Category),
+		}
+		if !h.Banker {
+			items[i].Bet = s.bet(h)
+		}
+	}
+	return items
+}
+
+// 下注分数,没有下注按1算
+func (s *settleService) bet(h model.SettleHand) int {
+	if h.Bet < 1 {
+		return 1
+	}
+	return h.Bet
+}
@@ -0,0 +1,156 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"niuniu/app/model"
+	"niuniu/library/card"
+	"niuniu/library/niu"
+)
+
+// 牛牛3倍,牛7到牛9两倍,其他1倍
+var settleRule = &model.RuleSet{
+	Multiples: map[string]int{"bullbull": 3, "bull9": 2, "bull8": 2, "bull7": 2},
+	Evaluator: niu.Default,
+}
+
+func settleHand(t *testing.T, name, cards string, banker bool, bet, grab int) model.SettleHand {
+	t.Helper()
+	cs, err := card.ParseAll(strings.Fields(cards))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := settleRule.Evaluator.Evaluate(cs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return model.SettleHand{Name: name, Hand: r, Banker: banker, Bet: bet, Grab: grab}
+}
+
+func deltas(items []model.SettleItem) map[string]int {
+	m := make(map[string]int, len(items))
+	for _, v := range items {
+		m[v.Name] = v.Delta
+	}
+	return m
+}
+
+func TestSettleBanker(t *testing.T) {
+	const (
+		bullbull = "10S JD QC 4H 6C"
+		bull8    = "10S JD QC 2H 6C"
+		bull1    = "10S JD QC 5H 6C"
+	)
+	cases := []struct {
+		name  string
+		hands func() []model.SettleHand
+		want  map[string]int
+	}{
+		{
+			// 闲家赢按闲家的牌型倍数,闲家输按庄家的牌型倍数
+			name: "倍数",
+			hands: func() []model.SettleHand {
+				return []model.SettleHand{
+					settleHand(t, "庄", bull8, true, 0, 0),
+					settleHand(t, "甲", bullbull, false, 2, 0),
+					settleHand(t, "乙", bull1, false, 3, 0),
+				}
+			},
+			want: map[string]int{"庄": 0, "甲": 6, "乙": -6},
+		},
+		{
+			// 抢庄倍数乘在每个闲家的输赢上
+			name: "抢庄倍数",
+			hands: func() []model.SettleHand {
+				return []model.SettleHand{
+					settleHand(t, "庄", bull8, true, 0, 3),
+					settleHand(t, "甲", bullbull, false, 2, 0),
+					settleHand(t, "乙", bull1, false, 3, 0),
+				}
+			},
+			want: map[string]int{"庄": 0, "甲": 18, "乙": -18},
+		},
+		{
+			// 牌一样大算庄家赢
+			name: "平局庄家赢",
+			hands: func() []model.SettleHand {
+				return []model.SettleHand{
+					settleHand(t, "庄", bull8, true, 0, 2),
+					settleHand(t, "甲", bull8, false, 5, 0),
+				}
+			},
+			want: map[string]int{"庄": 20, "甲": -20},
+		},
+		{
+			// 没有下注按1分算,没有抢庄按1倍算
+			name: "默认下注",
+			hands: func() []model.SettleHand {
+				return []model.SettleHand{
+					settleHand(t, "庄", bull1, true, 0, 0),
+					settleHand(t, "甲", bullbull, false, 0, 0),
+				}
+			},
+			want: map[string]int{"庄": -3, "甲": 3},
+		},
+	}
+	for _, c := range cases {
+		items, err := Settle.Settle(settleRule, c.hands())
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		got := deltas(items)
+		for name, want := range c.want {
+			if got[name] != want {
+				t.Errorf("%s: %s输赢%d,应该是%d", c.name, name, got[name], want)
+			}
+		}
+		sum := 0
+		for _, v := range got {
+			sum += v
+		}
+		if sum != 0 {
+			t.Errorf("%s: 输赢加起来是%d,应该是0", c.name, sum)
+		}
+	}
+}
+
+func TestSettleWinner(t *testing.T) {
+	rs := *settleRule
+	rs.Mode = model.SettleModeWinner
+	items, err := Settle.Settle(&rs, []model.SettleHand{
+		settleHand(t, "甲", "10S JD QC 5H 6C", false, 2, 0),
+		settleHand(t, "乙", "10S JD QC 2H 6C", false, 3, 0),
+		settleHand(t, "丙", "10S JD QC 5H 7C", false, 1, 0),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := deltas(items)
+	want := map[string]int{"甲": -4, "乙": 6, "丙": -2}
+	for name, v := range want {
+		if got[name] != v {
+			t.Errorf("%s输赢%d,应该是%d", name, got[name], v)
+		}
+	}
+}
+
+func TestSettleInvalid(t *testing.T) {
+	h := settleHand(t, "甲", "10S JD QC 5H 6C", false, 1, 0)
+	b := settleHand(t, "庄", "10S JD QC 5H 6C", true, 0, 0)
+	cases := map[string][]model.SettleHand{
+		"一个人":  {b},
+		"没有庄家": {h, h},
+		"两个庄家": {b, b},
+	}
+	for name, hands := range cases {
+		if _, err := Settle.Settle(settleRule, hands); err == nil {
+			t.Errorf("%s应该返回错误", name)
+		}
+	}
+	rs := *settleRule
+	rs.Mode = "unknown"
+	if _, err := Settle.Settle(&rs, []model.SettleHand{b, h}); err == nil {
+		t.Error("未知的结算模式应该返回错误")
+	}
+}
@@ -0,0 +1,1000 @@
+package service
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"niuniu/app/model"
+
+	"github.com/gogf/gf/errors/gerror"
+	"github.com/gogf/gf/frame/g"
+	"github.com/gogf/gf/os/gtime"
+	"github.com/gogf/gf/os/gtimer"
+)
+
+// 牌桌成员的连接,由接口层实现
+type Conn interface {
+	Send(msg model.ChatMsg) error
+	Close()
+	Account() string // 连接所在会话的钱包账号
+}
+
+const (
+	tableMinPlayers  = 2                // 最少几个人准备才开局
+	tableMinSeats    = 2                // 座位数最少2个
+	tableMaxSeats    = 10               // 座位数最多10个,一副牌最多够10个人
+	tableSeats       = 6                // 默认的座位数
+	tableWatchers    = 20               // 默认最多几个人观战
+	tableMaxWatchers = 100              // 观战人数上限
+	tableMaxDelay    = 300              // 观战延迟最多几秒
+	readyDelay       = 3 * time.Second  // 准备的人数够了之后多久开局
+	settleDelay      = 5 * time.Second  // 结算之后多久开始等待下一局
+	voteWindow       = 60 * time.Second // 解散房间的投票时间
+	reconnectGrace   = 60 * time.Second // 断线的玩家保留座位多久
+	historyMax       = 100              // 每个玩家保留最近多少条消息用于补发
+)
+
+// 发牌员的昵称,系统消息都以发牌员的名义发出
+const DealerName = "官方发牌员"
+
+// 房间里的玩家
+type tablePlayer struct {
+	name     string
+	conn     Conn
+	seat     int             // 座位号,从1开始,0为没有坐下
+	ready    bool            // 是否已经准备
+	revealed bool            // 本局是否已经亮牌
+	quit     bool            // 断开了连接,reconnectGrace内重新连上可以继续
+	away     time.Time       // 断开连接的时间
+	acked    int64           // 断开连接时的消息序号,重新连上时从这里补发
+	seq      int64           // 发给这个玩家的最后一条消息的序号
+	history  []model.ChatMsg // 最近historyMax条消息,按序号排列
+	pending  []model.ChatMsg // 观战延迟还没有发出的消息
+	idled    bool            // 本局抢庄、下注或者亮牌有没有超时
+	afk      int             // 连续超时的局数,有一局没有超时就清零
+}
+
+// 牌桌,一局的流程为:等待加入→准备开局→选庄→下注→发牌→亮牌→结算,
+// 每个阶段只接受该阶段的操作,阶段之间只能按PhaseTransitions切换。
+// 进入房间的玩家先坐下,坐下的玩家准备的人数够了之后开局,一局进行中坐下的玩家等下一局。
+// 没有坐下的玩家为观战,只能看到公开的消息,看不到没有亮的牌。
+type Table struct {
+	mu         sync.Mutex
+	id         int
+	code       string
+	opt        model.RoomOption
+	created    time.Time
+	used       bool // 是否有玩家加入过
+	board      *Scoreboard
+	vote       *Choice // 正在进行的解散投票
+	rule       *model.RuleSet
+	phase      string
+	round      int            // 局数编号,每开一局加1,用于丢弃过期的定时器回调
+	members    []*tablePlayer // 房间里的玩家
+	seats      []*tablePlayer // 座位,空座位为nil
+	players    []*tablePlayer // 本局玩家
+	owner      string
+	banker     string
+	grab       int
+	bets       map[string]int
+	pushed     map[string]bool
+	scored     bool // 本局已经记分了,不能再取消
+	last       []model.SettleItem
+	lastBanker string
+	deal       *Deal
+	choice     *Choice
+	timer      *gtimer.Entry
+	deadline   time.Time
+}
+
+// 按规则创建牌桌,id为房间编号,code为房号
+func NewTable(id int, code string, rs *model.RuleSet, opt model.RoomOption) *Table {
+	return &Table{
+		id:      id,
+		code:    code,
+		opt:     opt,
+		created: time.Now(),
+		board:   NewScoreboard(opt.Rounds),
+		seats:   make([]*tablePlayer, opt.Seats),
+		rule:    rs,
+		phase:   model.PhaseWaiting,
+		pushed:  make(map[string]bool),
+	}
+}
+
+// 当前阶段
+func (t *Table) Phase() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.phase
+}
+
+// 房间号
+func (t *Table) ID() int {
+	return t.id
+}
+
+// 牌桌使用的规则
+func (t *Table) Rule() *model.RuleSet {
+	return t.rule
+}
+
+// 房号
+func (t *Table) Code() string {
+	return t.code
+}
+
+// 牌桌信息
+func (t *Table) Info() model.RoomInfo {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.info()
+}
+
+func (t *Table) info() model.RoomInfo {
+	return model.RoomInfo{
+		Id:      t.id,
+		Code:    t.code,
+		Rule:    t.rule.Name,
+		Title:   t.rule.Title,
+		Phase:   t.phase,
+		Players: t.memberNames(),
+		Seats:   t.seatInfo(),
+		Watch:   t.watcherNames(),
+		Max:     len(t.seats),
+		Private: t.opt.Private,
+		Locked:  t.opt.Passcode != "",
+		Rounds:  t.opt.Rounds,
+		Round:   t.round,
+		Remain:  t.remain(),
+	}
+}
+
+// 整场牌局的战绩汇总
+func (t *Table) Summary() model.SessionSummary {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.board.Summary()
+}
+
+// 整场牌局战绩的文字说明
+func (t *Table) Score() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.board.Describe()
+}
+
+// 校验进房密码
+func (t *Table) Verify(passcode string) error {
+	if t.opt.Passcode != "" && t.opt.Passcode != passcode {
+		return gerror.New("房间密码不正确")
+	}
+	return nil
+}
+
+// 是否还能加入,私人房间不能快速加入
+func (t *Table) Joinable() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return !t.opt.Private && !t.finished() && t.phase != model.PhaseClosed && t.freeSeat() > 0
+}
+
+// 牌桌是否已经没人用了,已经解散了,有玩家加入过又都离开了,或者创建之后超过idle还没有人加入
+func (t *Table) Abandoned(idle time.Duration) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.phase == model.PhaseClosed {
+		return true
+	}
+	return len(t.members) == 0 && (t.used || time.Since(t.created) > idle)
+}
+
+// 牌桌内聊天,只发给同一个房间的玩家
+func (t *Table) Say(name, data string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.member(name) == nil {
+		return gerror.New("您不在这个牌桌上")
+	}
+	for _, p := range t.members {
+		t.write(p, model.ChatMsg{
+			Type: model.MsgSend,
+			Data: data,
+			From: name,
+		})
+	}
+	return nil
+}
+
+// 进入房间并坐到第一个空座位上,一局进行中进入的玩家等下一局
+func (t *Table) Join(name string, conn Conn) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.phase == model.PhaseClosed {
+		return gerror.New("房间已经解散了")
+	}
+	if t.member(name) != nil {
+		return gerror.New("您已经加入了")
+	}
+	if t.finished() {
+		return gerror.Newf("本房间%d局已经打完了", t.opt.Rounds)
+	}
+	seat := t.freeSeat()
+	if seat == 0 {
+		return gerror.New("牌桌人数已满,可以观战")
+	}
+	if err := t.checkWallet(name, conn); err != nil {
+		return err
+	}
+	p := &tablePlayer{name: name, conn: conn}
+	t.members = append(t.members, p)
+	t.used = true
+	if t.owner == "" {
+		t.owner = name
+	}
+	t.sit(p, seat)
+	t.broadcast(fmt.Sprintf("%s加入了牌桌,坐在%d号座位,输入准备开始游戏", name, seat))
+	if t.inRound() {
+		t.send(p, "本局已经开始了,请等待下一局")
+	}
+	return nil
+}
+
+// 离开房间,本局玩家在本局结束之前不能离开
+func (t *Table) Leave(name string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	p := t.member(name)
+	if p == nil {
+		return gerror.New("您不在这个牌桌上")
+	}
+	if t.playing(p) {
+		return gerror.New("本局还没有结束,不能离开")
+	}
+	t.remove(p)
+	t.broadcast(fmt.Sprintf("%s离开了牌桌,当前人数%d", name, len(t.members)))
+	t.checkReady()
+	return nil
+}
+
+// 断开连接,观战的玩家直接离开,坐下的玩家标记为断线并保留座位reconnectGrace,
+// 期间重新连上可以继续,返回是否还在牌桌上。conn已经被新的连接顶替时不处理
+func (t *Table) Quit(name string, conn Conn) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	p := t.member(name)
+	if p == nil {
+		return false
+	}
+	if p.conn != conn {
+		return true
+	}
+	if p.seat == 0 {
+		t.remove(p)
+		t.broadcast(fmt.Sprintf("%s断开了连接,当前人数%d", name, len(t.members)))
+		return false
+	}
+	p.quit = true
+	p.away = time.Now()
+	away := p.away
+	t.broadcast(fmt.Sprintf("%s断开了连接,%d秒内重新连上可以继续", name, reconnectGrace/time.Second))
+	// 断线的提示不用再补发给自己
+	p.acked = p.seq
+	t.checkReady()
+	gtimer.AddOnce(reconnectGrace, func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		if t.member(name) == p && p.quit && p.away == away {
+			t.expire(p)
+		}
+	})
+	return true
+}
+
+// 是否在房间里,断线还保留着座位的玩家也算
+func (t *Table) Has(name string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.member(name) != nil
+}
+
+// 抢庄出价,0为不抢
+func (t *Table) Grab(name string, multiple int) error {
+	t.mu.Lock()
+	if err := t.expect("抢庄", model.PhaseBanker); err != nil {
+		t.mu.Unlock()
+		return err
+	}
+	if t.rule.Banker != model.BankerGrab || t.choice == nil {
+		t.mu.Unlock()
+		return gerror.New("本局不是抢庄模式")
+	}
+	// 抢到庄的余额要够每个闲家都按最大分数下注并且拿到最大的牌型
+	if p := t.player(name); multiple > 0 && p != nil {
+		max := t.rule.Bets[len(t.rule.Bets)-1]
+		if err := Wallet.Check(p.conn.Account(), multiple*max*t.rule.MaxMultiple()*(len(t.players)-1)); err != nil {
+			t.mu.Unlock()
+			return err
+		}
+	}
+	c := t.choice
+	t.mu.Unlock()
+	// Choose结束时会回调牌桌,这里不能持有锁
+	return c.Choose(name, multiple)
+}
+
+// 闲家下注
+func (t *Table) Bet(name string, bet int) error {
+	t.mu.Lock()
+	if err := t.expect("下注", model.PhaseBetting); err != nil {
+		t.mu.Unlock()
+		return err
+	}
+	// 闲家的余额要够输最大的牌型
+	if p := t.player(name); p != nil && name != t.banker {
+		grab := t.grab
+		if grab < 1 {
+			grab = 1
+		}
+		if err := Wallet.Check(p.conn.Account(), bet*grab*t.rule.MaxMultiple()); err != nil {
+			t.mu.Unlock()
+			return err
+		}
+	}
+	c := t.choice
+	t.mu.Unlock()
+	return c.Choose(name, bet)
+}
+
+// 亮牌,所有玩家都亮牌之后结算
+func (t *Table) Reveal(name string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err := t.expect("亮牌", model.PhaseReveal); err != nil {
+		return err
+	}
+	p := t.player(name)
+	if p == nil {
+		return gerror.New("您不在本局玩家中")
+	}
+	if p.revealed {
+		return gerror.New("您已经亮过牌了")
+	}
+	p.revealed = true
+	cards, err := t.deal.Hand(name)
+	if err != nil {
+		return err
+	}
+	hand, err := t.rule.Evaluator.Evaluate(cards)
+	if err != nil {
+		return err
+	}
+	t.publish(model.MsgReveal, model.RevealPayload{
+		Room:  t.id,
+		Round: t.round,
+		Name:  name,
+		Hand:  hand,
+		Title: hand.Category.String(),
+	})
+	for _, v := range t.players {
+		if !v.revealed {
+			return nil
+		}
+	}
+	t.settle()
+	return nil
+}
+
+// 申请解散房间,坐下的玩家(包括断线的)投票决定,申请人默认同意,超时没有投票的视为拒绝
+func (t *Table) Dissolve(name string) error {
+	t.mu.Lock()
+	if err := t.expect("解散", model.PhaseWaiting, model.PhaseReady, model.PhaseBanker, model.PhaseBetting,
+		model.PhaseDealing, model.PhaseReveal, model.PhaseSettled); err != nil {
+		t.mu.Unlock()
+		return err
+	}
+	if p := t.member(name); p == nil || p.quit || p.seat == 0 {
+		t.mu.Unlock()
+		return gerror.New("只有坐下的玩家可以申请解散")
+	}
+	if t.vote != nil {
+		t.mu.Unlock()
+		return gerror.New("已经有人申请解散了,请输入同意或拒绝")
+	}
+	names := []string{}
+	for _, p := range t.seated() {
+		names = append(names, p.name)
+	}
+	rule := "过半数"
+	if t.opt.Vote == model.VoteUnanimous {
+		rule = "所有人"
+	}
+	t.broadcast(fmt.Sprintf("%s申请解散房间,需要%s同意,请在%d秒内输入同意或拒绝,超时视为拒绝", name, rule, voteWindow/time.Second))
+	var c *Choice
+	c = newChoice("投票", names, voteWindow, func(string, int) error { return nil }, 0, func(values map[string]int) {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		if t.vote != c {
+			return
+		}
+		t.vote = nil
+		t.tally(values)
+	})
+	t.vote = c
+	t.mu.Unlock()
+	return c.Choose(name, 1)
+}
+
+// 解散投票,agree为是否同意
+func (t *Table) Vote(name string, agree bool) error {
+	t.mu.Lock()
+	c := t.vote
+	t.mu.Unlock()
+	if c == nil {
+		return gerror.New("现在没有解散投票")
+	}
+	v := 0
+	if agree {
+		v = 1
+	}
+	return c.Choose(name, v)
+}
+
+// 统计解散投票的结果,通过了就解散房间
+func (t *Table) tally(values map[string]int) {
+	agree := 0
+	for _, v := range values {
+		agree += v
+	}
+	passed := agree*2 > len(values)
+	if t.opt.Vote == model.VoteUnanimous {
+		passed = agree == len(values)
+	}
+	res := fmt.Sprintf("解散投票结束,%d人同意,%d人拒绝", agree, len(values)-agree)
+	if !passed {
+		t.broadcast(res + ",继续游戏")
+		return
+	}
+	t.broadcast(res + ",房间解散")
+	t.close()
+}
+
+// 解散房间,取消正在进行的一局并记为取消,按已经打完的局发出最终战绩
+func (t *Table) close() {
+	interrupted := t.inRound() && !t.scored
+	if err := t.transit(model.PhaseClosed); err != nil {
+		g.Log().Error(err)
+		return
+	}
+	if interrupted {
+		t.board.Abort(t.round, "房间解散")
+	}
+	if t.choice != nil {
+		t.choice.Cancel()
+		t.choice = nil
+	}
+	if t.board.Played() == 0 {
+		t.broadcast("房间已解散,还没有打完一局")
+		return
+	}
+	t.publish(model.MsgSummary, t.board.Summary())
+	t.broadcast("房间已解散,最终战绩:</br>" + t.board.Describe())
+}
+
+// 开局,坐下并准备了的玩家参与本局,先告诉玩家当前的规则,明牌玩法先发几张牌,然后按上庄方式选庄
+func (t *Table) startRound() {
+	if err := t.transit(model.PhaseBanker); err != nil {
+		g.Log().Error(err)
+		return
+	}
+	t.players = nil
+	for _, p := range t.seated() {
+		if p.ready && !p.quit {
+			t.players = append(t.players, p)
+		}
+	}
+	t.round++
+	names := t.names()
+	if t.opt.Rounds > 0 {
+		t.broadcast(fmt.Sprintf("第%d局,共%d局", t.board.Played()+1, t.opt.Rounds))
+	}
+	t.broadcast(Rule.Describe(t.rule))
+	t.deal = Dealer.Start(names)
+	if t.rule.Reveal > 0 {
+		dealt, err := t.deal.Reveal(t.rule.Reveal)
+		if err != nil {
+			t.fail(err)
+			return
+		}
+		for _, p := range t.players {
+			t.notify(p, model.MsgDealt, model.DealtPayload{
+				Room:  t.id,
+				Round: t.round,
+				Cards: dealt[p.name],
+				New:   dealt[p.name],
+			})
+		}
+	}
+	req := model.BankerSelect{
+		Mode:    t.rule.Banker,
+		Players: names,
+		Owner:   t.owner,
+		Last:    t.lastBanker,
+		History: t.last,
+	}
+	// 通吃模式不需要庄家,所有人直接下注
+	if t.rule.Mode == model.SettleModeWinner {
+		t.banker = ""
+		t.grab = 1
+		t.startBetting(names)
+		return
+	}
+	if t.rule.Banker != model.BankerGrab {
+		t.chooseBanker(req)
+		return
+	}
+	t.broadcast(fmt.Sprintf("开始抢庄,请在%d秒内输入抢1到抢%d,输入不抢放弃,超时视为不抢", t.rule.GrabWindow, t.rule.GrabMax))
+	t.countdown(time.Duration(t.rule.GrabWindow) * time.Second)
+	t.choice = Banker.StartGrab(names, t.rule.GrabMax, time.Duration(t.rule.GrabWindow)*time.Second, t.guard(func(bids map[string]int) {
+		req.Bids = bids
+		t.chooseBanker(req)
+	}))
+}
+
+// 选出庄家,通知玩家后闲家开始下注
+func (t *Table) chooseBanker(req model.BankerSelect) {
+	res, err := Banker.Select(req)
+	if err != nil {
+		t.fail(err)
+		return
+	}
+	t.banker = res.Name
+	t.grab = res.Multiple
+	t.publish(model.MsgBankerChosen, model.BankerChosenPayload{
+		Room:     t.id,
+		Round:    t.round,
+		Name:     res.Name,
+		Mode:     req.Mode,
+		Multiple: res.Multiple,
+	})
+	players := []string{}
+	for _, name := range req.Players {
+		if name != res.Name {
+			players = append(players, name)
+		}
+	}
+	t.startBetting(players)
+}
+
+// 开始下注,下注结束后发牌
+func (t *Table) startBetting(players []string) {
+	if err := t.transit(model.PhaseBetting); err != nil {
+		t.fail(err)
+		return
+	}
+	// 根据上一局的结果计算每个玩家能不能推注
+	push := map[string]int{}
+	for _, v := range t.last {
+		if n := Bet.Push(t.rule, v, t.pushed[v.Name]); n > 0 {
+			push[v.Name] = n
+		}
+	}
+	t.each(func(name string) string {
+		for _, v := range players {
+			if v == name {
+				return Bet.Prompt(t.rule, push[name]) + ",输入下注N下注"
+			}
+		}
+		return "等待闲家下注"
+	})
+	t.countdown(time.Duration(t.rule.BetWindow) * time.Second)
+	t.choice = Bet.Start(t.rule, players, push, t.guard(func(values map[string]int) {
+		t.bets = values
+		t.pushed = make(map[string]bool)
+		items := []model.BetItem{}
+		for _, name := range players {
+			push := Bet.IsPush(t.rule, values[name])
+			t.pushed[name] = push
+			items = append(items, model.BetItem{Name: name, Bet: values[name], Push: push})
+		}
+		t.publish(model.MsgBetPlaced, model.BetPlacedPayload{
+			Room:  t.id,
+			Round: t.round,
+			Bets:  items,
+		})
+		t.dealCards()
+	}))
+}
+
+// 发牌,把每个玩家的牌补齐到五张,然后进入亮牌阶段
+func (t *Table) dealCards() {
+	if err := t.transit(model.PhaseDealing); err != nil {
+		t.fail(err)
+		return
+	}
+	dealt, err := t.deal.Complete()
+	if err != nil {
+		t.fail(err)
+		return
+	}
+	for _, p := range t.players {
+		cards, err := t.deal.Hand(p.name)
+		if err != nil {
+			t.fail(err)
+			return
+		}
+		hand, err := t.rule.Evaluator.Evaluate(cards)
+		if err != nil {
+			t.fail(err)
+			return
+		}
+		t.notify(p, model.MsgDealt, model.DealtPayload{
+			Room:     t.id,
+			Round:    t.round,
+			Cards:    cards,
+			New:      dealt[p.name],
+			Complete: true,
+			Hand:     &hand,
+			Title:    hand.Category.String(),
+		})
+	}
+	if err := t.transit(model.PhaseReveal); err != nil {
+		t.fail(err)
+		return
+	}
+	window := time.Duration(t.rule.RevealWindow) * time.Second
+	t.broadcast(fmt.Sprintf("请在%d秒内输入亮牌,超时自动亮牌", t.rule.RevealWindow))
+	t.after(window, t.autoReveal)
+}
+
+// 亮牌时间到了,没有亮牌的玩家自动亮牌,然后结算
+func (t *Table) autoReveal() {
+	for _, p := range t.players {
+		if p.revealed {
+			continue
+		}
+		p.idled = true
+		cards, err := t.deal.Hand(p.name)
+		if err != nil {
+			t.fail(err)
+			return
+		}
+		hand, err := t.rule.Evaluator.Evaluate(cards)
+		if err != nil {
+			t.fail(err)
+			return
+		}
+		p.revealed = true
+		t.publish(model.MsgReveal, model.RevealPayload{
+			Room:  t.id,
+			Round: t.round,
+			Name:  p.name,
+			Hand:  hand,
+			Title: hand.Category.String(),
+		})
+	}
+	t.settle()
+}
+
+// 结算,把所有人的牌与输赢发给房间里的所有玩家
+func (t *Table) settle() {
+	if err := t.transit(model.PhaseSettled); err != nil {
+		t.fail(err)
+		return
+	}
+	hands := []model.SettleHand{}
+	for _, p := range t.players {
+		cards, err := t.deal.Hand(p.name)
+		if err != nil {
+			t.fail(err)
+			return
+		}
+		hand, err := t.rule.Evaluator.Evaluate(cards)
+		if err != nil {
+			t.fail(err)
+			return
+		}
+		hands = append(hands, model.SettleHand{
+			Name:   p.name,
+			Hand:   hand,
+			Banker: p.name == t.banker,
+			Bet:    t.bets[p.name],
+			Grab:   t.grab,
+		})
+	}
+	// 庄家模式下每个闲家单独跟庄家比,通吃模式最大的牌赢
+	items, err := Settle.Settle(t.rule, hands)
+	if err != nil {
+		t.fail(err)
+		return
+	}
+	// 所有人的余额一起更新,余额不够输时最多输光,庄家或者赢家按实际收到与赔出的筹码计算
+	accounts := make(map[string]string, len(t.players))
+	for _, p := range t.players {
+		accounts[p.name] = p.conn.Account()
+	}
+	hub := items[0]
+	deltas := make(map[string]int, len(items))
+	for _, v := range items {
+		deltas[accounts[v.Name]] = v.Delta
+		if v.Banker || (!hub.Banker && v.Delta > hub.Delta) {
+			hub = v
+		}
+	}
+	applied, balances := Wallet.Settle(accounts[hub.Name], deltas)
+	capped := false
+	for i, v := range items {
+		if delta := applied[accounts[v.Name]]; delta != v.Delta {
+			items[i].Delta = delta
+			capped = true
+		}
+	}
+	payload := model.SettlementPayload{
+		Room:   t.id,
+		Round:  t.round,
+		Banker: t.banker,
+		Items:  make([]model.SettlementItem, len(items)),
+	}
+	for i, v := range items {
+		payload.Items[i] = model.SettlementItem{
+			SettleItem: v,
+			Title:      v.Hand.Category.String(),
+			Balance:    balances[accounts[v.Name]],
+		}
+	}
+	t.publish(model.MsgSettlement, payload)
+	if capped {
+		t.broadcast("有玩家的余额不够输,按实际的余额结算")
+	}
+	t.last = items
+	t.lastBanker = t.banker
+	t.board.Record(items)
+	t.scored = true
+	if t.finished() {
+		t.publish(model.MsgSummary, t.board.Summary())
+		t.broadcast(fmt.Sprintf("本房间%d局已经打完了,最终战绩:</br>%s", t.opt.Rounds, t.board.Describe()))
+	} else {
+		t.broadcast(fmt.Sprintf("%d秒后可以准备下一局,输入战绩查看本房间的战绩", settleDelay/time.Second))
+	}
+	t.after(settleDelay, t.reset)
+}
+
+// 清空本局数据,回到等待加入阶段,断线超时的玩家离开牌桌,本局玩家需要重新准备,
+// 一局进行中准备好的玩家人数够了就直接倒计时开局
+func (t *Table) reset() {
+	if err := t.transit(model.PhaseWaiting); err != nil {
+		g.Log().Error(err)
+		return
+	}
+	for _, p := range t.players {
+		p.ready = false
+		p.revealed = false
+		t.count(p)
+		if p.quit && time.Since(p.away) >= reconnectGrace {
+			t.expire(p)
+		} else if p.afk >= t.rule.AfkMax {
+			t.idle(p)
+		}
+	}
+	t.players = nil
+	t.banker = ""
+	t.grab = 0
+	t.bets = nil
+	t.scored = false
+	t.deal = nil
+	t.choice = nil
+	if t.finished() {
+		return
+	}
+	t.broadcast("输入准备开始下一局,输入离开可以离开牌桌")
+	t.announce()
+	t.checkReady()
+}
+
+// 切换阶段,不合法的切换返回错误,切换时停止上一个阶段的定时器
+func (t *Table) transit(to string) error {
+	for _, v := range model.PhaseTransitions[t.phase] {
+		if v == to {
+			t.stopTimer()
+			t.phase = to
+			return nil
+		}
+	}
+	return gerror.Newf("牌桌不能从%s阶段切换到%s阶段", model.PhaseNames[t.phase], model.PhaseNames[to])
+}
+
+// 检查当前阶段是否允许该操作
+func (t *Table) expect(action string, phases ...string) error {
+	for _, v := range phases {
+		if t.phase == v {
+			return nil
+		}
+	}
+	return gerror.Newf("当前是%s阶段,不能%s", model.PhaseNames[t.phase], action)
+}
+
+// 阶段定时器,到时间后如果还在同一局的同一阶段就执行f
+func (t *Table) after(d time.Duration, f func()) {
+	t.stopTimer()
+	round, phase := t.round, t.phase
+	t.countdown(d)
+	t.timer = gtimer.AddOnce(d, func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		if t.round == round && t.phase == phase {
+			f()
+		}
+	})
+}
+
+// 设置当前阶段的倒计时,并通知房间里的玩家
+func (t *Table) countdown(d time.Duration) {
+	t.deadline = time.Now().Add(d)
+	t.announce()
+}
+
+// 通知房间里的玩家当前阶段与剩余时间
+func (t *Table) announce() {
+	t.publish(model.MsgPhase, model.PhasePayload{
+		Room:   t.id,
+		Round:  t.round,
+		Phase:  t.phase,
+		Remain: t.remain(),
+	})
+}
+
+// 当前阶段剩余的毫秒数,没有倒计时为0
+func (t *Table) remain() int64 {
+	if t.deadline.IsZero() {
+		return 0
+	}
+	d := time.Until(t.deadline)
+	if d < 0 {
+		return 0
+	}
+	return int64(d / time.Millisecond)
+}
+
+func (t *Table) stopTimer() {
+	if t.timer != nil {
+		t.timer.Close()
+		t.timer = nil
+	}
+	t.deadline = time.Time{}
+}
+
+// 包装收集选择的回调,回调时加锁,并丢弃不是同一局同一阶段的回调,超时没有选择的玩家记一次超时
+func (t *Table) guard(f func(values map[string]int)) func(values map[string]int) {
+	round, phase := t.round, t.phase
+	return func(values map[string]int) {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		if t.round == round && t.phase == phase {
+			if t.choice != nil {
+				t.timeout(t.choice, values)
+			}
+			t.choice = nil
+			f(values)
+		}
+	}
+}
+
+// 设置了局数的房间是否已经打完,取消的局不算
+func (t *Table) finished() bool {
+	return t.opt.Rounds > 0 && t.board.Played() >= t.opt.Rounds
+}
+
+func (t *Table) player(name string) *tablePlayer {
+	for _, p := range t.players {
+		if p.name == name {
+			return p
+		}
+	}
+	return nil
+}
+
+func (t *Table) member(name string) *tablePlayer {
+	for _, p := range t.members {
+		if p.name == name {
+			return p
+		}
+	}
+	return nil
+}
+
+func (t *Table) memberNames() []string {
+	names := make([]string, len(t.members))
+	for i, p := range t.members {
+		names[i] = p.name
+	}
+	return names
+}
+
+// 移出房间,让出座位,房主离开后由最早进入房间的玩家当房主
+func (t *Table) remove(p *tablePlayer) {
+	t.stand(p)
+	for i, v := range t.members {
+		if v == p {
+			t.members = append(t.members[:i], t.members[i+1:]...)
+			break
+		}
+	}
+	if t.owner == p.name {
+		t.owner = ""
+		if len(t.members) > 0 {
+			t.owner = t.members[0].name
+		}
+	}
+}
+
+func (t *Table) names() []string {
+	names := make([]string, len(t.players))
+	for i, p := range t.players {
+		names[i] = p.name
+	}
+	return names
+}
+
+// 向房间里的所有玩家发送发牌员的消息
+func (t *Table) broadcast(data string) {
+	for _, p := range t.members {
+		t.send(p, data)
+	}
+}
+
+// 向本局的每个玩家单独发送发牌员的消息,消息内容由玩家昵称生成
+func (t *Table) each(data func(name string) string) {
+	for _, p := range t.players {
+		t.send(p, data(p.name))
+	}
+}
+
+func (t *Table) send(p *tablePlayer, data string) {
+	t.notify(p, model.MsgSend, data)
+}
+
+// 向房间里的所有玩家发送结构化的消息
+func (t *Table) publish(typ string, data interface{}) {
+	for _, p := range t.members {
+		t.notify(p, typ, data)
+	}
+}
+
+// 向一个玩家发送结构化的消息
+func (t *Table) notify(p *tablePlayer, typ string, data interface{}) {
+	t.write(p, model.ChatMsg{
+		Type: typ,
+		Data: data,
+		From: DealerName,
+	})
+}
+
+// 给消息编号并保留到history,断线的玩家重新连上后再补发
+func (t *Table) write(p *tablePlayer, msg model.ChatMsg) {
+	p.seq++
+	msg.Room = t.id
+	msg.Seq = p.seq
+	if msg.Time == 0 {
+		msg.Time = gtime.TimestampMilli()
+	}
+	p.history = append(p.history, msg)
+	if len(p.history) > historyMax {
+		p.history = p.history[len(p.history)-historyMax:]
+	}
+	if p.quit {
+		return
+	}
+	t.deliver(p, msg)
+}
+
+// 发出已经编号的消息,设置了观战延迟时观战的玩家延迟收到消息
+func (t *Table) deliver(p *tablePlayer, msg model.ChatMsg) {
+	if p.seat == 0 && t.opt.Delay > 0 {
+		t.delay(p, msg)
+		return
+	}
+	if err := p.conn.Send(msg); err != nil {
+		g.Log().Error(err)
+	}
+}
@@ -0,0 +1,188 @@
+package service
+
+import (
+	"sync"
+
+	"github.com/gogf/gf/encoding/gjson"
+	"github.com/gogf/gf/errors/gerror"
+	"github.com/gogf/gf/frame/g"
+	"github.com/gogf/gf/os/gfile"
+)
+
+// 筹码钱包服务,按钱包账号记录余额,账号跟着会话走,不跟着昵称走。
+// 玩家第一次连上时开户并按config.toml中wallet.initial发放初始筹码,余额保存在wallet.file配置的文件里
+var Wallet = walletService{
+	balances: make(map[string]int),
+}
+
+type walletService struct {
+	mu       sync.Mutex
+	once     sync.Once
+	file     string         // 保存余额的文件,为空时只保存在内存里
+	balances map[string]int // 钱包账号对应的余额
+}
+
+var (
+	errInsufficient = gerror.New("余额不足")    // 余额不足
+	errNoWallet     = gerror.New("钱包账号不存在") // 还没有开户
+)
+
+// 开户,已经开过户时不重复发放初始筹码,返回余额
+func (s *walletService) Open(account string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.load()
+	balance, ok := s.balances[account]
+	if !ok {
+		balance = g.Cfg().GetInt("wallet.initial", 1000)
+		s.balances[account] = balance
+		s.save()
+	}
+	return balance
+}
+
+// 查询余额,没有开户时返回错误,不会开户
+func (s *walletService) Balance(account string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.get(account)
+}
+
+// 增加筹码,返回增加之后的余额
+func (s *walletService) Credit(account string, amount int) (int, error) {
+	if amount <= 0 {
+		return 0, gerror.New("筹码数量必须大于0")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	balance, err := s.get(account)
+	if err != nil {
+		return 0, err
+	}
+	s.balances[account] = balance + amount
+	s.save()
+	return s.balances[account], nil
+}
+
+// 扣除筹码,余额不足时不扣,返回扣除之后的余额
+func (s *walletService) Debit(account string, amount int) (int, error) {
+	if amount <= 0 {
+		return 0, gerror.New("筹码数量必须大于0")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	balance, err := s.get(account)
+	if err != nil {
+		return 0, err
+	}
+	if balance < amount {
+		return balance, errInsufficient
+	}
+	s.balances[account] = balance - amount
+	s.save()
+	return s.balances[account], nil
+}
+
+// 检查余额是否够amount
+func (s *walletService) Check(account string, amount int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	balance, err := s.get(account)
+	if err != nil {
+		return err
+	}
+	if balance < amount {
+		return gerror.Newf("余额不足,需要%d筹码,您只有%d筹码", amount, balance)
+	}
+	return nil
+}
+
+// 按一局的输赢一起更新余额,hub为跟其他人结算的账号(庄家或者通吃的赢家)。
+// 输给hub的玩家最多输光余额,hub不够赔时按比例赔给赢了的玩家,余额不会小于0。
+// 返回实际的输赢与更新之后的余额
+func (s *walletService) Settle(hub string, deltas map[string]int) (map[string]int, map[string]int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.load()
+	applied := make(map[string]int, len(deltas))
+	// 先收输了的玩家的筹码
+	received, owed := 0, 0
+	for account, delta := range deltas {
+		if account == hub {
+			continue
+		}
+		if delta < 0 {
+			loss := -delta
+			if balance := s.balances[account]; loss > balance {
+				loss = balance
+			}
+			applied[account] = -loss
+			received += loss
+		} else {
+			owed += delta
+		}
+	}
+	// 再赔给赢了的玩家,hub的余额加上收到的筹码不够赔时按比例赔
+	available := s.balances[hub] + received
+	paid := 0
+	for account, delta := range deltas {
+		if account == hub || delta < 0 {
+			continue
+		}
+		if owed > available {
+			delta = delta * available / owed
+		}
+		applied[account] = delta
+		paid += delta
+	}
+	if _, ok := deltas[hub]; ok {
+		applied[hub] = received - paid
+	}
+	balances := make(map[string]int, len(applied))
+	for account, delta := range applied {
+		s.balances[account] += delta
+		balances[account] = s.balances[account]
+	}
+	s.save()
+	return applied, balances
+}
+
+// 账号的余额,没有开户时返回错误,调用时需要持有锁
+func (s *walletService) get(account string) (int, error) {
+	if account == "" {
+		return 0, gerror.New("您还没有钱包,请先设置昵称")
+	}
+	s.load()
+	balance, ok := s.balances[account]
+	if !ok {
+		return 0, errNoWallet
+	}
+	return balance, nil
+}
+
+// 从wallet.file加载余额,只加载一次,调用时需要持有锁
+func (s *walletService) load() {
+	s.once.Do(func() {
+		s.file = g.Cfg().GetString("wallet.file")
+		if s.file == "" || !gfile.Exists(s.file) {
+			return
+		}
+		if err := gjson.DecodeTo(gfile.GetBytes(s.file), &s.balances); err != nil {
+			g.Log().Errorf("钱包文件%s格式错误: %v", s.file, err)
+		}
+	})
+}
+
+// 把余额写到wallet.file,调用时需要持有锁
+func (s *walletService) save() {
+	if s.file == "" {
+		return
+	}
+	b, err := gjson.Encode(s.balances)
+	if err == nil {
+		err = gfile.PutBytes(s.file, b)
+	}
+	if err != nil {
+		g.Log().Error(err)
+	}
+}
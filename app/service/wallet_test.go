@@ -0,0 +1,67 @@
+package service
+
+import (
+	"testing"
+)
+
+func TestWalletSettle(t *testing.T) {
+	cases := []struct {
+		name     string
+		balances map[string]int
+		hub      string
+		deltas   map[string]int
+		applied  map[string]int
+	}{
+		{
+			name:     "余额足够",
+			balances: map[string]int{"庄": 100, "甲": 100, "乙": 100},
+			hub:      "庄",
+			deltas:   map[string]int{"庄": -10, "甲": 30, "乙": -20},
+			applied:  map[string]int{"庄": -10, "甲": 30, "乙": -20},
+		},
+		{
+			name:     "闲家不够输",
+			balances: map[string]int{"庄": 100, "甲": 5, "乙": 100},
+			hub:      "庄",
+			deltas:   map[string]int{"庄": 20, "甲": -30, "乙": 10},
+			applied:  map[string]int{"庄": -5, "甲": -5, "乙": 10},
+		},
+		{
+			name:     "庄家不够赔按比例",
+			balances: map[string]int{"庄": 10, "甲": 100, "乙": 100, "丙": 100},
+			hub:      "庄",
+			deltas:   map[string]int{"庄": -50, "甲": 40, "乙": 20, "丙": -10},
+			applied:  map[string]int{"庄": -9, "甲": 13, "乙": 6, "丙": -10},
+		},
+		{
+			name:     "通吃的赢家",
+			balances: map[string]int{"甲": 0, "乙": 3, "丙": 100},
+			hub:      "甲",
+			deltas:   map[string]int{"甲": 30, "乙": -10, "丙": -20},
+			applied:  map[string]int{"甲": 23, "乙": -3, "丙": -20},
+		},
+	}
+	for _, c := range cases {
+		// 不加载也不保存wallet.file
+		s := walletService{balances: c.balances}
+		s.once.Do(func() {})
+		before := make(map[string]int, len(c.balances))
+		for k, v := range c.balances {
+			before[k] = v
+		}
+		applied, balances := s.Settle(c.hub, c.deltas)
+		total := 0
+		for name, want := range c.applied {
+			if applied[name] != want {
+				t.Errorf("%s: %s的输赢为%d,应该是%d", c.name, name, applied[name], want)
+			}
+			if balances[name] != before[name]+want || balances[name] < 0 {
+				t.Errorf("%s: %s的余额为%d", c.name, name, balances[name])
+			}
+			total += applied[name]
+		}
+		if total > 0 {
+			t.Errorf("%s: 赢的比输的多%d", c.name, total)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"niuniu/app/model"
+
+	"github.com/gogf/gf/errors/gerror"
+	"github.com/gogf/gf/frame/g"
+	"github.com/gogf/gf/os/gtimer"
+)
+
+// 进入房间观战,观战的玩家可以随时坐下
+func (t *Table) Watch(name string, conn Conn) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.phase == model.PhaseClosed {
+		return gerror.New("房间已经解散了")
+	}
+	if t.member(name) != nil {
+		return gerror.New("您已经加入了")
+	}
+	if len(t.watchers()) >= t.opt.Watchers {
+		return gerror.New("观战人数已满")
+	}
+	p := &tablePlayer{name: name, conn: conn}
+	t.members = append(t.members, p)
+	t.used = true
+	if t.owner == "" {
+		t.owner = name
+	}
+	t.broadcast(fmt.Sprintf("%s进入房间观战", name))
+	if t.opt.Delay > 0 {
+		t.send(p, fmt.Sprintf("观战的消息会延迟%d秒", t.opt.Delay))
+	}
+	return nil
+}
+
+// 观战的玩家
+func (t *Table) watchers() []*tablePlayer {
+	players := []*tablePlayer{}
+	for _, p := range t.members {
+		if p.seat == 0 {
+			players = append(players, p)
+		}
+	}
+	return players
+}
+
+func (t *Table) watcherNames() []string {
+	names := []string{}
+	for _, p := range t.watchers() {
+		names = append(names, p.name)
+	}
+	return names
+}
+
+// 延迟发送消息,每条消息到时间后按顺序发出最早的一条
+func (t *Table) delay(p *tablePlayer, msg model.ChatMsg) {
+	p.pending = append(p.pending, msg)
+	gtimer.AddOnce(time.Duration(t.opt.Delay)*time.Second, func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		if len(p.pending) == 0 {
+			return
+		}
+		msg := p.pending[0]
+		p.pending = p.pending[1:]
+		if p.quit || t.member(p.name) != p {
+			return
+		}
+		if err := p.conn.Send(msg); err != nil {
+			g.Log().Error(err)
+		}
+	})
+}
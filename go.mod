@@ -5,7 +5,7 @@ go 1.11
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/gogf/gf v1.16.1
-	github.com/gorilla/websocket v1.4.2 // indirect
+	github.com/gorilla/websocket v1.4.2
 	github.com/grokify/html-strip-tags-go v0.0.0-20200322061010-ea0c1cf2f119 // indirect
 	github.com/kr/text v0.2.0 // indirect
 	github.com/niemeyer/pretty v0.0.0-20200227124842-a10e7caefd8e // indirect
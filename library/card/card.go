@@ -0,0 +1,280 @@
+// 扑克牌的基础表示。
+// 统一了中文写法("黑桃A")、ASCII写法("AS"、"10H")与Unicode扑克牌字符三种格式,
+// 发牌、算牛与消息协议都使用同一个Card类型。
+package card
+
+import (
+	"strings"
+
+	"github.com/gogf/gf/errors/gerror"
+)
+
+// Suit 花色,数值越大花色越大(方块<梅花<红桃<黑桃),王没有花色
+type Suit int8
+
+const (
+	NoSuit  Suit = iota // 无花色,大小王使用
+	Diamond             // 方块
+	Club                // 梅花
+	Heart               // 红桃
+	Spade               // 黑桃
+)
+
+// Rank 牌面大小,1-13为A到K,14为小王,15为大王
+type Rank int8
+
+const (
+	Ace Rank = iota + 1
+	Two
+	Three
+	Four
+	Five
+	Six
+	Seven
+	Eight
+	Nine
+	Ten
+	Jack
+	Queen
+	King
+	LittleJoker // 小王
+	BigJoker    // 大王
+)
+
+// Card 一张牌
+type Card struct {
+	Suit Suit
+	Rank Rank
+}
+
+var (
+	// 花色顺序与Suit常量一一对应
+	suitNames = []string{"", "方块", "梅花", "红桃", "黑桃"}
+	suitASCII = []string{"", "D", "C", "H", "S"}
+	// Unicode扑克牌区块中每个花色A的码位
+	suitGlyph  = []rune{0, 0x1F0C1, 0x1F0D1, 0x1F0B1, 0x1F0A1}
+	rankNames  = []string{"", "A", "2", "3", "4", "5", "6", "7", "8", "9", "10", "J", "Q", "K"}
+	jokerName  = map[Rank]string{LittleJoker: "小王", BigJoker: "大王"}
+	jokerASCII = map[Rank]string{LittleJoker: "LJ", BigJoker: "BJ"}
+	jokerGlyph = map[Rank]rune{LittleJoker: 0x1F0CF, BigJoker: 0x1F0BF}
+
+	// Suits 四种花色,按从大到小排列,与原来的发牌顺序一致
+	Suits = []Suit{Spade, Heart, Club, Diamond}
+)
+
+// New 创建一张普通牌
+func New(s Suit, r Rank) Card {
+	return Card{Suit: s, Rank: r}
+}
+
+// IsJoker 是否为大小王
+func (c Card) IsJoker() bool {
+	return c.Rank == LittleJoker || c.Rank == BigJoker
+}
+
+// IsFace 是否为花牌(J Q K)
+func (c Card) IsFace() bool {
+	return c.Rank >= Jack && c.Rank <= King
+}
+
+// Valid 判断牌是否合法
+func (c Card) Valid() bool {
+	if c.IsJoker() {
+		return c.Suit == NoSuit
+	}
+	return c.Rank >= Ace && c.Rank <= King && c.Suit >= Diamond && c.Suit <= Spade
+}
+
+// Point 算牛用的点数,A为1,10和花牌都为10,大小王按10算
+func (c Card) Point() int {
+	if c.Rank >= Ten {
+		return 10
+	}
+	return int(c.Rank)
+}
+
+// Weight 比大小用的权重,先比牌面再比花色,跟原来dian返回的值一致
+func (c Card) Weight() int {
+	return int(c.Rank)*10 + int(c.Suit)
+}
+
+// String 中文写法,如"黑桃A"、"方块10"、"大王"
+func (c Card) String() string {
+	if c.IsJoker() {
+		return jokerName[c.Rank]
+	}
+	if !c.Valid() {
+		return "?"
+	}
+	return suitNames[c.Suit] + rankNames[c.Rank]
+}
+
+// ASCII 英文写法,如"AS"、"10H",小王为"LJ",大王为"BJ"
+func (c Card) ASCII() string {
+	if c.IsJoker() {
+		return jokerASCII[c.Rank]
+	}
+	if !c.Valid() {
+		return "?"
+	}
+	return rankNames[c.Rank] + suitASCII[c.Suit]
+}
+
+// Glyph Unicode扑克牌字符,如"🂡"
+func (c Card) Glyph() string {
+	if c.IsJoker() {
+		return string(jokerGlyph[c.Rank])
+	}
+	if !c.Valid() {
+		return "?"
+	}
+	offset := rune(c.Rank - 1)
+	// Unicode在J和Q之间有一张骑士(C),这里要跳过
+	if c.Rank >= Queen {
+		offset++
+	}
+	return string(suitGlyph[c.Suit] + offset)
+}
+
+// MarshalText 序列化时使用中文写法,保持和原来发给客户端的内容一致
+func (c Card) MarshalText() ([]byte, error) {
+	if !c.Valid() {
+		return nil, gerror.Newf("无效的牌: %d/%d", c.Suit, c.Rank)
+	}
+	return []byte(c.String()), nil
+}
+
+// UnmarshalText 反序列化时三种写法都可以识别
+func (c *Card) UnmarshalText(b []byte) error {
+	v, err := Parse(string(b))
+	if err != nil {
+		return err
+	}
+	*c = v
+	return nil
+}
+
+// Parse 解析一张牌,支持中文写法、ASCII写法与Unicode字符
+func Parse(s string) (Card, error) {
+	s = strings.TrimSpace(s)
+	if c, ok := parseChinese(s); ok {
+		return c, nil
+	}
+	if c, ok := parseASCII(s); ok {
+		return c, nil
+	}
+	if c, ok := parseGlyph(s); ok {
+		return c, nil
+	}
+	return Card{}, gerror.Newf("无法识别的牌: %s", s)
+}
+
+// MustParse 解析一张牌,失败时panic,只用于写死的牌
+func MustParse(s string) Card {
+	c, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// ParseAll 批量解析
+func ParseAll(ss []string) ([]Card, error) {
+	cards := make([]Card, 0, len(ss))
+	for _, s := range ss {
+		c, err := Parse(s)
+		if err != nil {
+			return nil, err
+		}
+		cards = append(cards, c)
+	}
+	return cards, nil
+}
+
+// Strings 把一手牌转成中文写法
+func Strings(cards []Card) []string {
+	ss := make([]string, len(cards))
+	for i, c := range cards {
+		ss[i] = c.String()
+	}
+	return ss
+}
+
+// Join 把一手牌用sep连接成中文写法的字符串
+func Join(cards []Card, sep string) string {
+	return strings.Join(Strings(cards), sep)
+}
+
+func parseChinese(s string) (Card, bool) {
+	for r, name := range jokerName {
+		if s == name {
+			return Card{Rank: r}, true
+		}
+	}
+	for i := Diamond; i <= Spade; i++ {
+		if strings.HasPrefix(s, suitNames[i]) {
+			if r, ok := parseRank(strings.TrimPrefix(s, suitNames[i])); ok {
+				return Card{Suit: i, Rank: r}, true
+			}
+		}
+	}
+	return Card{}, false
+}
+
+func parseASCII(s string) (Card, bool) {
+	s = strings.ToUpper(s)
+	for r, name := range jokerASCII {
+		if s == name {
+			return Card{Rank: r}, true
+		}
+	}
+	if len(s) < 2 {
+		return Card{}, false
+	}
+	rank, suit := s[:len(s)-1], s[len(s)-1:]
+	for i := Diamond; i <= Spade; i++ {
+		if suit == suitASCII[i] {
+			if r, ok := parseRank(rank); ok {
+				return Card{Suit: i, Rank: r}, true
+			}
+		}
+	}
+	return Card{}, false
+}
+
+func parseGlyph(s string) (Card, bool) {
+	rs := []rune(s)
+	if len(rs) != 1 {
+		return Card{}, false
+	}
+	for r, g := range jokerGlyph {
+		if rs[0] == g {
+			return Card{Rank: r}, true
+		}
+	}
+	for i := Diamond; i <= Spade; i++ {
+		offset := rs[0] - suitGlyph[i]
+		// 0-13为A到K加骑士,骑士(11)不使用
+		if offset < 0 || offset > 13 || offset == 11 {
+			continue
+		}
+		r := Rank(offset + 1)
+		if offset > 11 {
+			r--
+		}
+		return Card{Suit: i, Rank: r}, true
+	}
+	return Card{}, false
+}
+
+func parseRank(s string) (Rank, bool) {
+	if s == "T" {
+		return Ten, true
+	}
+	for i := Ace; i <= King; i++ {
+		if rankNames[i] == s {
+			return i, true
+		}
+	}
+	return 0, false
+}
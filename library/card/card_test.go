@@ -0,0 +1,79 @@
+package card
+
+import "testing"
+
+// 所有的牌,包括大小王
+func allCards() []Card {
+	cards := []Card{{Rank: LittleJoker}, {Rank: BigJoker}}
+	for _, s := range Suits {
+		for r := Ace; r <= King; r++ {
+			cards = append(cards, New(s, r))
+		}
+	}
+	return cards
+}
+
+func TestParseRoundTrip(t *testing.T) {
+	for _, c := range allCards() {
+		for _, s := range []string{c.String(), c.ASCII(), c.Glyph()} {
+			got, err := Parse(s)
+			if err != nil {
+				t.Errorf("%s: %v", s, err)
+				continue
+			}
+			if got != c {
+				t.Errorf("%s解析为%s,应该是%s", s, got, c)
+			}
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		s    string
+		want Card
+	}{
+		{"黑桃A", New(Spade, Ace)},
+		{"方块10", New(Diamond, Ten)},
+		{"10h", New(Heart, Ten)},
+		{"TC", New(Club, Ten)},
+		{" qs ", New(Spade, Queen)},
+		{"大王", Card{Rank: BigJoker}},
+		{"lj", Card{Rank: LittleJoker}},
+		{"🂡", New(Spade, Ace)},
+		{"🃝", New(Club, Queen)},
+	}
+	for _, c := range cases {
+		got, err := Parse(c.s)
+		if err != nil {
+			t.Errorf("%q: %v", c.s, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("%q解析为%s,应该是%s", c.s, got, c.want)
+		}
+	}
+	for _, s := range []string{"", "X", "1S", "11H", "红桃0", "黑桃B", "🂬"} {
+		if c, err := Parse(s); err == nil {
+			t.Errorf("%q不应该能解析,解析为%s", s, c)
+		}
+	}
+}
+
+func TestCardText(t *testing.T) {
+	c := New(Heart, Ten)
+	b, err := c.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "红桃10" {
+		t.Errorf("序列化为%s,应该是红桃10", b)
+	}
+	var got Card
+	if err := got.UnmarshalText([]byte("10H")); err != nil || got != c {
+		t.Errorf("10H反序列化为%s,%v", got, err)
+	}
+	if _, err := (Card{}).MarshalText(); err == nil {
+		t.Error("无效的牌序列化应该返回错误")
+	}
+}
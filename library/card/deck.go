@@ -0,0 +1,71 @@
+package card
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/gogf/gf/errors/gerror"
+)
+
+// ErrNotEnough 牌堆剩余的牌不够发
+var ErrNotEnough = gerror.New("牌堆剩余的牌不够发了")
+
+func init() {
+	rand.Seed(time.Now().UnixNano())
+}
+
+// Deck 牌堆,发牌从牌堆顶部依次取出
+type Deck struct {
+	cards []Card
+}
+
+// NewDeck 初始化牌堆,decks为使用几副牌(小于1按1副算),joker为是否包含大小王。
+// 返回的牌堆没有洗过,需要的话调用Shuffle。
+func NewDeck(decks int, joker bool) *Deck {
+	if decks < 1 {
+		decks = 1
+	}
+	d := &Deck{}
+	for n := 0; n < decks; n++ {
+		for _, s := range Suits {
+			for r := Ace; r <= King; r++ {
+				d.cards = append(d.cards, Card{Suit: s, Rank: r})
+			}
+		}
+		if joker {
+			d.cards = append(d.cards, Card{Rank: BigJoker}, Card{Rank: LittleJoker})
+		}
+	}
+	return d
+}
+
+// Shuffle 洗牌
+func (d *Deck) Shuffle() *Deck {
+	rand.Shuffle(len(d.cards), func(i, j int) {
+		d.cards[i], d.cards[j] = d.cards[j], d.cards[i]
+	})
+	return d
+}
+
+// Deal 从牌堆中发n张牌,剩余不够时返回ErrNotEnough并且不发任何牌
+func (d *Deck) Deal(n int) ([]Card, error) {
+	if n > len(d.cards) {
+		return nil, ErrNotEnough
+	}
+	hand := make([]Card, n)
+	copy(hand, d.cards[:n])
+	d.cards = d.cards[n:]
+	return hand, nil
+}
+
+// Len 牌堆剩余的牌数
+func (d *Deck) Len() int {
+	return len(d.cards)
+}
+
+// Cards 牌堆剩余的牌,返回的是副本
+func (d *Deck) Cards() []Card {
+	cards := make([]Card, len(d.cards))
+	copy(cards, d.cards)
+	return cards
+}
@@ -0,0 +1,110 @@
+// 牛牛牌型计算。
+// 五张牌中任选三张凑成10的倍数即为有牛,剩下两张点数和的个位数为牛几,个位为0即为牛牛。
+package niu
+
+import (
+	"fmt"
+
+	"github.com/gogf/gf/errors/gerror"
+)
+
+// Category 牌型
+type Category int8
+
+const (
+	NoBull    Category = iota // 没有牛
+	Bull1                     // 牛1
+	Bull2                     // 牛2
+	Bull3                     // 牛3
+	Bull4                     // 牛4
+	Bull5                     // 牛5
+	Bull6                     // 牛6
+	Bull7                     // 牛7
+	Bull8                     // 牛8
+	Bull9                     // 牛9
+	BullBull                  // 牛牛
+	FiveFace                  // 五花牛,五张都是JQK,原来的五朵金花
+	FourFace                  // 四花牛,四张JQK加一张10
+	FiveSmall                 // 五小牛,五张都小于5并且加起来不超过10
+	Bomb                      // 炸弹牛,四张一样的牌
+	Gourd                     // 葫芦牛,三张一样加一对
+	Straight                  // 顺子牛,五张连续的牌
+	Flush                     // 同花牛,五张同一花色
+)
+
+// 牌型的中文名与配置用的英文key
+var categoryNames = map[Category][2]string{
+	NoBull:    {"没有牛", "none"},
+	BullBull:  {"牛牛", "bullbull"},
+	FiveFace:  {"五花牛", "fiveface"},
+	FourFace:  {"四花牛", "fourface"},
+	FiveSmall: {"五小牛", "fivesmall"},
+	Bomb:      {"炸弹牛", "bomb"},
+	Gourd:     {"葫芦牛", "gourd"},
+	Straight:  {"顺子牛", "straight"},
+	Flush:     {"同花牛", "flush"},
+}
+
+func init() {
+	for c := Bull1; c <= Bull9; c++ {
+		categoryNames[c] = [2]string{fmt.Sprintf("牛%d", c), fmt.Sprintf("bull%d", c)}
+	}
+}
+
+// Categories 所有牌型,按默认从小到大排列
+func Categories() []Category {
+	cs := make([]Category, 0, len(categoryNames))
+	for c := NoBull; int(c) < len(categoryNames); c++ {
+		cs = append(cs, c)
+	}
+	return cs
+}
+
+// IsSpecial 是否为特殊牌型,特殊牌型总是比牛牛大,相互之间的大小由规则决定
+func (c Category) IsSpecial() bool {
+	return c > BullBull && int(c) < len(categoryNames)
+}
+
+// String 中文名,如"牛7"、"牛牛"
+func (c Category) String() string {
+	if n, ok := categoryNames[c]; ok {
+		return n[0]
+	}
+	return fmt.Sprintf("未知牌型%d", c)
+}
+
+// Key 配置文件与协议中使用的英文名,如"bull7"
+func (c Category) Key() string {
+	if n, ok := categoryNames[c]; ok {
+		return n[1]
+	}
+	return fmt.Sprintf("unknown%d", c)
+}
+
+// MarshalText 序列化为英文名
+func (c Category) MarshalText() ([]byte, error) {
+	if _, ok := categoryNames[c]; !ok {
+		return nil, gerror.Newf("未知牌型: %d", c)
+	}
+	return []byte(c.Key()), nil
+}
+
+// UnmarshalText 中文名与英文名都可以识别
+func (c *Category) UnmarshalText(b []byte) error {
+	v, err := ParseCategory(string(b))
+	if err != nil {
+		return err
+	}
+	*c = v
+	return nil
+}
+
+// ParseCategory 根据中文名或英文名获取牌型
+func ParseCategory(s string) (Category, error) {
+	for c, n := range categoryNames {
+		if s == n[0] || s == n[1] {
+			return c, nil
+		}
+	}
+	return NoBull, gerror.Newf("未知牌型: %s", s)
+}
@@ -0,0 +1,269 @@
+package niu
+
+import (
+	"niuniu/library/card"
+
+	"github.com/gogf/gf/errors/gerror"
+)
+
+// HandSize 一手牌的张数
+const HandSize = 5
+
+// Result 一手牌的计算结果
+type Result struct {
+	Category Category    `json:"category"` // 牌型
+	Bull     int         `json:"bull"`     // 牛几,0为没牛,10为牛牛
+	Base     []card.Card `json:"base"`     // 凑成10的倍数的三张牌,没牛时为空
+	Score    []card.Card `json:"score"`    // 算点数的两张牌,没牛时为空
+	Key      card.Rank   `json:"key"`      // 牌型相同时先比的牌面,炸弹牛为四张的牌面,葫芦牛为三张的牌面,其他牌型为0
+	Max      card.Card   `json:"max"`      // 最大的牌,牌型相同时比这张牌
+	Cards    []card.Card `json:"cards"`    // 整手牌
+	Rank     int         `json:"-"`        // 牌型在当前规则下的排名,越大越好
+}
+
+// matcher 牌型判断表中的一项
+type matcher struct {
+	Category Category
+	Match    func(r *Result) bool
+}
+
+// Evaluator 牌型计算器,按判断表从大到小依次匹配,第一个满足的即为该手牌的牌型
+type Evaluator struct {
+	table []matcher
+}
+
+// 特殊牌型的判断方法
+var specialMatch = map[Category]func(r *Result) bool{
+	FiveFace: func(r *Result) bool {
+		return countFace(r.Cards) == HandSize
+	},
+	FourFace: func(r *Result) bool {
+		return countFace(r.Cards) == HandSize-1 && countRank(r.Cards)[card.Ten] == 1
+	},
+	FiveSmall: func(r *Result) bool {
+		sum := 0
+		for _, c := range r.Cards {
+			if c.Point() >= 5 {
+				return false
+			}
+			sum += c.Point()
+		}
+		return sum <= 10
+	},
+	Bomb: func(r *Result) bool {
+		for _, n := range countRank(r.Cards) {
+			if n == 4 {
+				return true
+			}
+		}
+		return false
+	},
+	Gourd: func(r *Result) bool {
+		ranks := countRank(r.Cards)
+		if len(ranks) != 2 {
+			return false
+		}
+		for _, n := range ranks {
+			if n != 2 && n != 3 {
+				return false
+			}
+		}
+		return true
+	},
+	Straight: func(r *Result) bool {
+		ranks := countRank(r.Cards)
+		if len(ranks) != HandSize {
+			return false
+		}
+		// 10JQKA也算顺子
+		if ranks[card.Ace] == 1 && ranks[card.Ten] == 1 && countFace(r.Cards) == 3 {
+			return true
+		}
+		low, high := card.BigJoker, card.Rank(0)
+		for rank := range ranks {
+			if rank > card.King {
+				return false
+			}
+			if rank < low {
+				low = rank
+			}
+			if rank > high {
+				high = rank
+			}
+		}
+		return int(high-low) == HandSize-1
+	},
+	Flush: func(r *Result) bool {
+		for _, c := range r.Cards {
+			if c.IsJoker() || c.Suit != r.Cards[0].Suit {
+				return false
+			}
+		}
+		return true
+	},
+}
+
+// DefaultSpecials 默认启用的特殊牌型,从大到小排列
+var DefaultSpecials = []Category{FiveSmall, Bomb, FiveFace, FourFace}
+
+// Default 默认的计算器
+var Default, _ = NewEvaluator(DefaultSpecials...)
+
+// NewEvaluator 创建计算器,specials为启用的特殊牌型,按从大到小排列,都排在牛牛前面
+func NewEvaluator(specials ...Category) (*Evaluator, error) {
+	e := &Evaluator{}
+	for _, c := range specials {
+		match, ok := specialMatch[c]
+		if !ok {
+			return nil, gerror.Newf("%s不是特殊牌型", c)
+		}
+		for _, m := range e.table {
+			if m.Category == c {
+				return nil, gerror.Newf("特殊牌型%s重复配置", c)
+			}
+		}
+		e.table = append(e.table, matcher{c, match})
+	}
+	for c := BullBull; c >= Bull1; c-- {
+		e.table = append(e.table, matcher{c, isBull(int(c))})
+	}
+	e.table = append(e.table, matcher{NoBull, func(r *Result) bool { return true }})
+	return e, nil
+}
+
+// Categories 当前计算器支持的牌型,从大到小排列
+func (e *Evaluator) Categories() []Category {
+	cs := make([]Category, len(e.table))
+	for i, m := range e.table {
+		cs[i] = m.Category
+	}
+	return cs
+}
+
+// Evaluate 使用默认计算器计算
+func Evaluate(cards []card.Card) (Result, error) {
+	return Default.Evaluate(cards)
+}
+
+// Evaluate 计算一手牌的牌型
+func (e *Evaluator) Evaluate(cards []card.Card) (Result, error) {
+	if len(cards) != HandSize {
+		return Result{}, gerror.Newf("一手牌必须是%d张,当前为%d张", HandSize, len(cards))
+	}
+	r := Result{Cards: make([]card.Card, HandSize)}
+	copy(r.Cards, cards)
+	for i, c := range cards {
+		if !c.Valid() {
+			return Result{}, gerror.Newf("第%d张牌无效", i+1)
+		}
+		if i == 0 || c.Weight() > r.Max.Weight() {
+			r.Max = c
+		}
+	}
+	split(&r)
+	for i, m := range e.table {
+		if m.Match(&r) {
+			r.Category = m.Category
+			r.Rank = len(e.table) - i
+			break
+		}
+	}
+	switch r.Category {
+	case Bomb:
+		r.Key = rankOf(r.Cards, 4)
+	case Gourd:
+		r.Key = rankOf(r.Cards, 3)
+	}
+	return r, nil
+}
+
+// split 枚举五选三的所有组合,取牛最大的一种拆法
+func split(r *Result) {
+	for i := 0; i < HandSize-2; i++ {
+		for j := i + 1; j < HandSize-1; j++ {
+			for k := j + 1; k < HandSize; k++ {
+				c := r.Cards
+				if (c[i].Point()+c[j].Point()+c[k].Point())%10 != 0 {
+					continue
+				}
+				var score []card.Card
+				sum := 0
+				for n := 0; n < HandSize; n++ {
+					if n != i && n != j && n != k {
+						score = append(score, c[n])
+						sum += c[n].Point()
+					}
+				}
+				bull := sum % 10
+				if bull == 0 {
+					bull = 10
+				}
+				if bull > r.Bull {
+					r.Bull = bull
+					r.Base = []card.Card{c[i], c[j], c[k]}
+					r.Score = score
+				}
+			}
+		}
+	}
+}
+
+// isBull 牛几的判断
+func isBull(n int) func(r *Result) bool {
+	return func(r *Result) bool {
+		return r.Bull == n
+	}
+}
+
+// countFace 花牌(JQK)的张数
+func countFace(cards []card.Card) (n int) {
+	for _, c := range cards {
+		if c.IsFace() {
+			n++
+		}
+	}
+	return
+}
+
+// countRank 每种牌面的张数
+func countRank(cards []card.Card) map[card.Rank]int {
+	ranks := make(map[card.Rank]int)
+	for _, c := range cards {
+		ranks[c.Rank]++
+	}
+	return ranks
+}
+
+// rankOf 正好有n张的牌面,没有时返回0
+func rankOf(cards []card.Card, n int) card.Rank {
+	for rank, count := range countRank(cards) {
+		if count == n {
+			return rank
+		}
+	}
+	return 0
+}
+
+// Compare 比较两手牌,先比牌型,牌型相同先比Key(炸弹、葫芦的牌面),再比最大的牌。a大返回1,b大返回-1,一样大返回0
+func Compare(a, b Result) int {
+	switch {
+	case a.Rank > b.Rank:
+		return 1
+	case a.Rank < b.Rank:
+		return -1
+	case a.Key > b.Key:
+		return 1
+	case a.Key < b.Key:
+		return -1
+	case a.Max.Weight() > b.Max.Weight():
+		return 1
+	case a.Max.Weight() < b.Max.Weight():
+		return -1
+	}
+	return 0
+}
+
+// Beats 是否比另一手牌大
+func (r Result) Beats(o Result) bool {
+	return Compare(r, o) > 0
+}
@@ -0,0 +1,136 @@
+package niu
+
+import (
+	"strings"
+	"testing"
+
+	"niuniu/library/card"
+)
+
+// 解析空格分隔的ASCII写法,如"10S JD QC 5H 6C"
+func hand(t *testing.T, s string) []card.Card {
+	t.Helper()
+	cards, err := card.ParseAll(strings.Fields(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cards
+}
+
+// 启用所有特殊牌型,按从大到小排列
+var all, _ = NewEvaluator(FiveSmall, Bomb, FiveFace, FourFace, Gourd, Straight, Flush)
+
+func TestEvaluateCategories(t *testing.T) {
+	cases := []struct {
+		cards string
+		want  Category
+		bull  int
+	}{
+		{"AS 2D 5C 8H 9S", NoBull, 0},
+		{"10S JD QC 5H 6C", Bull1, 1},
+		{"10S JD QC 5H 7C", Bull2, 2},
+		{"10S JD QC 5H 8C", Bull3, 3},
+		{"10S JD QC 5H 9C", Bull4, 4},
+		{"10S JD QC 2H 3C", Bull5, 5},
+		{"10S JD QC 2H 4C", Bull6, 6},
+		{"10S JD QC 3H 4C", Bull7, 7},
+		{"10S JD QC 2H 6C", Bull8, 8},
+		{"10S JD QC 4H 5C", Bull9, 9},
+		{"10S JD QC 4H 6C", BullBull, 10},
+		{"AS AD 2C 2H 3S", FiveSmall, 0},
+		{"5S 5H 5C 5D 9S", Bomb, 0},
+		{"JS QD KC JH QS", FiveFace, 10},
+		{"10S JD QC KH JS", FourFace, 10},
+		{"8S 8H 8C 3D 3S", Gourd, 0},
+		{"3S 4D 5C 6H 7S", Straight, 0},
+		{"AS 2D 3C 4H 5S", Straight, 0},
+		{"10S JD QC KH AS", Straight, 0},
+		{"2H 5H 8H 9H KH", Flush, 0},
+	}
+	for _, c := range cases {
+		r, err := all.Evaluate(hand(t, c.cards))
+		if err != nil {
+			t.Fatalf("%s: %v", c.cards, err)
+		}
+		if r.Category != c.want {
+			t.Errorf("%s: 牌型为%s,应该是%s", c.cards, r.Category, c.want)
+		}
+		if c.bull > 0 && r.Bull != c.bull {
+			t.Errorf("%s: 牛%d,应该是牛%d", c.cards, r.Bull, c.bull)
+		}
+	}
+}
+
+// 同时满足多种特殊牌型时按启用的顺序取排在前面的
+func TestEvaluatePrecedence(t *testing.T) {
+	cases := []struct {
+		specials []Category
+		cards    string
+		want     Category
+	}{
+		{[]Category{FiveSmall, Gourd}, "AS AD AC 2H 2S", FiveSmall},
+		{[]Category{Gourd, FiveSmall}, "AS AD AC 2H 2S", Gourd},
+		{[]Category{Straight, Flush}, "3H 4H 5H 6H 7H", Straight},
+		{[]Category{Flush, Straight}, "3H 4H 5H 6H 7H", Flush},
+		{[]Category{Bomb, FiveFace}, "KS KH KC KD QS", Bomb},
+		{[]Category{FiveFace, Bomb}, "KS KH KC KD QS", FiveFace},
+		// 没有启用的特殊牌型按普通的牛计算,10JQKA是牛1
+		{DefaultSpecials, "10S JD QC KH AS", Bull1},
+		{nil, "AS AD 2C 2H 3S", NoBull},
+	}
+	for _, c := range cases {
+		e, err := NewEvaluator(c.specials...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r, err := e.Evaluate(hand(t, c.cards))
+		if err != nil {
+			t.Fatalf("%s: %v", c.cards, err)
+		}
+		if r.Category != c.want {
+			t.Errorf("%v %s: 牌型为%s,应该是%s", c.specials, c.cards, r.Category, c.want)
+		}
+	}
+}
+
+func TestEvaluateInvalid(t *testing.T) {
+	if _, err := all.Evaluate(hand(t, "AS 2D 5C 8H")); err == nil {
+		t.Error("四张牌应该返回错误")
+	}
+	if _, err := all.Evaluate([]card.Card{{}, {}, {}, {}, {}}); err == nil {
+		t.Error("无效的牌应该返回错误")
+	}
+	if _, err := NewEvaluator(Bull5); err == nil {
+		t.Error("牛5不是特殊牌型,应该返回错误")
+	}
+	if _, err := NewEvaluator(Bomb, Bomb); err == nil {
+		t.Error("重复的特殊牌型应该返回错误")
+	}
+}
+
+func TestCompare(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want int
+	}{
+		{"10S JD QC 4H 6C", "10S JD QC 4H 5C", 1},
+		{"5S 5H 5C 5D 9S", "10S JD QC 4H 6C", 1},
+		// 牌型一样时比最大的一张牌,先比牌面再比花色
+		{"10S JD QC 5H 6C", "10H JS QD 5C 6D", 1},
+		{"10S JD KC 5H 6C", "10H JS QD 5C 6D", 1},
+		{"AS 2D 5C 8H 9S", "AS 2D 5C 8H 9S", 0},
+		// 炸弹牛比四张的牌面,葫芦牛比三张的牌面,不比最大的一张牌
+		{"6S 6H 6C 6D 2S", "5S 5H 5C 5D KS", 1},
+		{"QS QH QC 3D 3S", "2S 2H 2C KD KS", 1},
+	}
+	for _, c := range cases {
+		a, _ := all.Evaluate(hand(t, c.a))
+		b, _ := all.Evaluate(hand(t, c.b))
+		if got := Compare(a, b); got != c.want {
+			t.Errorf("%s 比 %s: %d,应该是%d", c.a, c.b, got, c.want)
+		}
+		if got := Compare(b, a); got != -c.want {
+			t.Errorf("%s 比 %s: %d,应该是%d", c.b, c.a, got, -c.want)
+		}
+	}
+}
@@ -17,6 +17,10 @@ func init() {
 			service.Middleware.CORS,
 		)
 		group.ALL("/chat", api.Chat)
+		// 房间接口,通过房号加入房间时连接/chat/websocket?code=房号&passcode=密码
+		group.ALL("/room", api.Room)
+		// 筹码钱包接口
+		group.ALL("/wallet", api.Wallet)
 		/* group.ALL("/user", api.User)
 		group.Group("/", func(group *ghttp.RouterGroup) {
 			group.Middleware(service.Middleware.Auth)